@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// accountPurgeInterval is how often accounts past their deletion grace
+// period are removed.
+const accountPurgeInterval = time.Hour
+
+// purgeDeletedAccounts hard-deletes accounts whose grace period has run out,
+// once at startup and then every interval until ctx is done. Everything
+// the user owns cascades, except failed-login records, which are keyed by
+// email rather than user and are removed here.
+func (cfg *apiConfig) purgeDeletedAccounts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := cfg.db.PurgeDeletedUsers(ctx)
+		if err != nil {
+			log.Printf("Couldn't purge deleted accounts: %s", err)
+		}
+		for _, user := range purged {
+			if err := cfg.db.ClearLoginAttempts(ctx, emailThrottleKey(user.Email)); err != nil {
+				log.Printf("Couldn't clear failed logins for purged user %s: %s", user.ID, err)
+			}
+		}
+		if len(purged) > 0 {
+			log.Printf("Purged %d deleted accounts", len(purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxAPIKeysPerUser   = 25
+	maxAPIKeyNameLength = 100
+	// apiKeyTouchTimeout bounds recording a key's last use, which happens
+	// in the background.
+	apiKeyTouchTimeout = 5 * time.Second
+)
+
+// apiKeyGrantableScopes are the scopes an API key may carry. Keys can't
+// manage the account, so a leaked key can't mint more keys or lock the
+// owner out, and can't use admin routes, which would bypass two-factor.
+var apiKeyGrantableScopes = []string{auth.ScopeChirpsRead, auth.ScopeChirpsWrite, auth.ScopeModerate}
+
+// errInvalidAPIKey is returned by authenticateAPIKey for unknown, revoked
+// and expired keys alike.
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// APIKey is an API key as shown to its owner. The key itself is only ever
+// returned when it is created.
+type APIKey struct {
+	ID uuid.UUID `json:"id"`
+	// Prefix is the start of the key, enough to recognise it.
+	Prefix    string    `json:"prefix"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt and LastUsedAt are omitted for keys that never expire and
+	// keys that haven't been used.
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// apiKeyFromDB converts a database row into its API representation.
+func apiKeyFromDB(key database.ApiKey) APIKey {
+	apiKey := APIKey{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    auth.APIKeyPrefix + key.KeyID,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+	}
+	if key.ExpiresAt.Valid {
+		apiKey.ExpiresAt = &key.ExpiresAt.Time
+	}
+	if key.LastUsedAt.Valid {
+		apiKey.LastUsedAt = &key.LastUsedAt.Time
+	}
+	return apiKey
+}
+
+// authenticateAPIKey checks an API key and returns the principal it acts
+// as. The key's scopes are narrowed to what the owner's current role
+// allows, so a demoted moderator's keys lose the moderate scope.
+func (cfg *apiConfig) authenticateAPIKey(ctx context.Context, key string) (auth.Principal, error) {
+	keyID, err := auth.ParseAPIKey(key)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	apiKey, err := cfg.db.GetAPIKeyByKeyID(ctx, keyID)
+	if err != nil {
+		return auth.Principal{}, errInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashAPIKey(key)), []byte(apiKey.KeyHash)) != 1 {
+		return auth.Principal{}, errInvalidAPIKey
+	}
+
+	user, err := cfg.db.GetUserByID(ctx, apiKey.UserID)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if user.DeleteAfter.Valid {
+		return auth.Principal{}, errors.New("account is scheduled for deletion")
+	}
+
+	role := auth.Role(user.Role)
+	scopes := []string{}
+	for _, scope := range apiKey.Scopes {
+		if slices.Contains(role.Scopes(), scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	// Recording use mustn't slow the request down or fail it.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), apiKeyTouchTimeout)
+		defer cancel()
+		if err := cfg.db.TouchAPIKey(ctx, apiKey.ID); err != nil {
+			log.Printf("Couldn't record use of API key %s: %s", apiKey.ID, err)
+		}
+	}()
+
+	return auth.Principal{
+		UserID: user.ID,
+		Role:   role,
+		Scopes: scopes,
+	}, nil
+}
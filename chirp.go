@@ -1,7 +1,10 @@
 package main
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/ItSpecOps/go-server/internal/database"
 	"github.com/google/uuid"
 )
 
@@ -9,11 +12,63 @@ type Chirp struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	Body     string    `json:"body"`
-	UserID  uuid.UUID `json:"user_id"`
+	Body      string    `json:"body"`
+	UserID    uuid.UUID `json:"user_id"`
+	// Edited chirps have earlier bodies listed under
+	// /api/chirps/{chirpID}/revisions.
+	Edited   bool       `json:"edited"`
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	// Replies name the chirp they answer and the first chirp of their
+	// conversation. Either is left out once that chirp is deleted.
+	InReplyToID *uuid.UUID `json:"in_reply_to_id,omitempty"`
+	RootID      *uuid.UUID `json:"root_id,omitempty"`
+	ReplyCount  int        `json:"reply_count"`
+	// Author is only set when the request asks for ?expand=author.
+	Author chirpAuthor `json:"author,omitzero"`
+}
+
+// chirpAuthor is a chirp's embedded author profile. It is left out of the
+// JSON unless it was asked for, and is null when the author can't be shown,
+// such as an account pending deletion.
+type chirpAuthor struct {
+	expanded bool
+	profile  *Profile
+}
+
+func (a chirpAuthor) IsZero() bool {
+	return !a.expanded
+}
+
+func (a chirpAuthor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.profile)
+}
+
+// chirpFromDB converts a database row into its API representation.
+func chirpFromDB(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:         dbChirp.ID,
+		CreatedAt:  dbChirp.CreatedAt,
+		UpdatedAt:  dbChirp.UpdatedAt,
+		Body:       dbChirp.Body,
+		UserID:     dbChirp.UserID,
+		ReplyCount: int(dbChirp.ReplyCount),
+	}
+	if dbChirp.EditedAt.Valid {
+		chirp.Edited = true
+		chirp.EditedAt = &dbChirp.EditedAt.Time
+	}
+	if dbChirp.InReplyToID.Valid {
+		chirp.InReplyToID = &dbChirp.InReplyToID.UUID
+	}
+	if dbChirp.RootID.Valid {
+		chirp.RootID = &dbChirp.RootID.UUID
+	}
+	return chirp
 }
 
 type createChirpParams struct {
-	Body string `json:"body"`
+	Body   string    `json:"body"`
 	UserID uuid.UUID `json:"user_id"`
+	// InReplyToID makes the chirp a reply.
+	InReplyToID *uuid.UUID `json:"in_reply_to_id"`
 }
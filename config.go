@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/mailer"
+	"github.com/ItSpecOps/go-server/internal/moderation"
+	"github.com/ItSpecOps/go-server/internal/validation"
+)
+
+// envInt reads an integer setting, falling back to def when unset.
+func envInt(name string, def int) (int, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return n, nil
+}
+
+// envDuration reads a duration setting such as "15m", falling back to def
+// when unset.
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
+}
+
+// envBool reads a boolean setting, falling back to def when unset.
+func envBool(name string, def bool) (bool, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", name, err)
+	}
+	return b, nil
+}
+
+// loadPasswordHasher reads argon2id tuning from ARGON2_MEMORY_KIB,
+// ARGON2_ITERATIONS and ARGON2_PARALLELISM. Raising them makes every
+// existing hash get upgraded on its owner's next login.
+func loadPasswordHasher() (auth.PasswordHasher, error) {
+	params := auth.DefaultArgon2Params
+
+	memory, err := envInt("ARGON2_MEMORY_KIB", int(params.Memory))
+	if err != nil {
+		return auth.PasswordHasher{}, err
+	}
+	iterations, err := envInt("ARGON2_ITERATIONS", int(params.Iterations))
+	if err != nil {
+		return auth.PasswordHasher{}, err
+	}
+	parallelism, err := envInt("ARGON2_PARALLELISM", int(params.Parallelism))
+	if err != nil {
+		return auth.PasswordHasher{}, err
+	}
+	if memory < 8*1024 || iterations < 1 || parallelism < 1 || parallelism > 255 {
+		return auth.PasswordHasher{}, fmt.Errorf("argon2 parameters too weak or out of range: m=%d t=%d p=%d", memory, iterations, parallelism)
+	}
+
+	params.Memory = uint32(memory)
+	params.Iterations = uint32(iterations)
+	params.Parallelism = uint8(parallelism)
+	return auth.PasswordHasher{Params: params}, nil
+}
+
+// loadPasswordPolicy reads PASSWORD_MIN_LENGTH (default 8),
+// PASSWORD_MAX_LENGTH (default 128), PASSWORD_DISALLOW_EMAIL (default true)
+// and PASSWORD_BREACHED_DIR, a directory of Pwned Passwords range files. The
+// breach check is skipped when no directory is configured.
+func loadPasswordPolicy() (auth.PasswordPolicy, error) {
+	minLength, err := envInt("PASSWORD_MIN_LENGTH", 8)
+	if err != nil {
+		return auth.PasswordPolicy{}, err
+	}
+	maxLength, err := envInt("PASSWORD_MAX_LENGTH", 128)
+	if err != nil {
+		return auth.PasswordPolicy{}, err
+	}
+	if minLength < 1 || maxLength < minLength {
+		return auth.PasswordPolicy{}, fmt.Errorf("invalid password length limits: min=%d max=%d", minLength, maxLength)
+	}
+	disallowEmail, err := envBool("PASSWORD_DISALLOW_EMAIL", true)
+	if err != nil {
+		return auth.PasswordPolicy{}, err
+	}
+
+	policy := auth.PasswordPolicy{
+		MinLength:     minLength,
+		MaxLength:     maxLength,
+		DisallowEmail: disallowEmail,
+	}
+	if dir := os.Getenv("PASSWORD_BREACHED_DIR"); dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return auth.PasswordPolicy{}, fmt.Errorf("PASSWORD_BREACHED_DIR: %w", err)
+		}
+		if !info.IsDir() {
+			return auth.PasswordPolicy{}, fmt.Errorf("PASSWORD_BREACHED_DIR: %s is not a directory", dir)
+		}
+		policy.Breached = &auth.BreachedPasswords{Dir: dir}
+	}
+	return policy, nil
+}
+
+// loadChirpPolicy reads CHIRP_MAX_LENGTH (default 140), the longest chirp
+// in characters as a reader counts them.
+func loadChirpPolicy() (validation.TextPolicy, error) {
+	maxLength, err := envInt("CHIRP_MAX_LENGTH", 140)
+	if err != nil {
+		return validation.TextPolicy{}, err
+	}
+	if maxLength < 1 {
+		return validation.TextPolicy{}, fmt.Errorf("invalid CHIRP_MAX_LENGTH: %d", maxLength)
+	}
+	return validation.TextPolicy{MaxLength: maxLength, AllowNewlines: true}, nil
+}
+
+// loadLoginThrottles reads LOGIN_LOCKOUT_THRESHOLD (default 10 failures)
+// and LOGIN_LOCKOUT_DURATION (default 15m) for accounts. IP addresses get a
+// fixed, more lenient backoff and never lock out.
+func loadLoginThrottles() (loginThrottles, error) {
+	lockoutAfter, err := envInt("LOGIN_LOCKOUT_THRESHOLD", 10)
+	if err != nil {
+		return loginThrottles{}, err
+	}
+	lockoutDuration, err := envDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	if err != nil {
+		return loginThrottles{}, err
+	}
+	if lockoutAfter < 1 || lockoutDuration <= 0 {
+		return loginThrottles{}, fmt.Errorf("invalid login lockout settings: threshold=%d duration=%s", lockoutAfter, lockoutDuration)
+	}
+
+	return loginThrottles{
+		email: loginThrottle{
+			freeAttempts:    3,
+			baseDelay:       time.Second,
+			maxDelay:        time.Minute,
+			lockoutAfter:    lockoutAfter,
+			lockoutDuration: lockoutDuration,
+			resetAfter:      24 * time.Hour,
+		},
+		ip: loginThrottle{
+			freeAttempts: 20,
+			baseDelay:    time.Second,
+			maxDelay:     5 * time.Minute,
+			resetAfter:   time.Hour,
+		},
+	}, nil
+}
+
+// loadMailer picks how outgoing mail is delivered from MAILER:
+//
+//   - "stdout" prints each message, for development. It is the default when
+//     platform is "dev"; anywhere else MAILER must be set, since messages
+//     carry sign-in and password reset tokens that don't belong in logs.
+//   - "file" appends each message to MAILER_FILE.
+//   - "smtp" sends through SMTP_HOST and SMTP_PORT (default 587), logging
+//     in with SMTP_USERNAME and SMTP_PASSWORD when set.
+//
+// MAIL_FROM sets the From header.
+func loadMailer(platform string) (mailer.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Chirpy <no-reply@localhost>"
+	}
+
+	kind := os.Getenv("MAILER")
+	if kind == "" {
+		if platform != "dev" {
+			return nil, fmt.Errorf("MAILER must be set unless PLATFORM=dev")
+		}
+		kind = "stdout"
+	}
+
+	switch kind {
+	case "stdout":
+		return mailer.NewWriter(os.Stdout, from), nil
+	case "file":
+		path := os.Getenv("MAILER_FILE")
+		if path == "" {
+			return nil, fmt.Errorf("MAILER_FILE must be set when MAILER=file")
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("MAILER_FILE: %w", err)
+		}
+		return mailer.NewWriter(f, from), nil
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST must be set when MAILER=smtp")
+		}
+		port, err := envInt("SMTP_PORT", 587)
+		if err != nil {
+			return nil, err
+		}
+		return &mailer.SMTP{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	default:
+		return nil, fmt.Errorf("MAILER: unknown mailer %q", kind)
+	}
+}
+
+// loadModeration reads chirp moderation rules from MODERATION_CONFIG, a
+// JSON rules file that is reloaded when it changes. It is checked every
+// MODERATION_RELOAD_INTERVAL (default 10s), and an edit that doesn't load
+// leaves the previous rules in force. Without a file, the built-in word
+// list applies.
+func loadModeration() (moderation.Source, error) {
+	path := os.Getenv("MODERATION_CONFIG")
+	if path == "" {
+		return moderation.Static(moderation.DefaultPipeline()), nil
+	}
+	interval, err := envDuration("MODERATION_RELOAD_INTERVAL", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := moderation.NewWatcher(path)
+	if err != nil {
+		return nil, err
+	}
+	go watcher.Watch(context.Background(), interval, func(err error) {
+		if err != nil {
+			log.Printf("Keeping previous moderation rules: %s", err)
+			return
+		}
+		log.Printf("Reloaded moderation rules from %s", path)
+	})
+	return watcher, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// trimPage cuts rows, fetched with one extra, down to pageSize, and reports
+// whether the extra row showed there is another page.
+func trimPage[T any](rows []T, pageSize int) ([]T, bool) {
+	if len(rows) > pageSize {
+		return rows[:pageSize], true
+	}
+	return rows, false
+}
+
+// chirpCursor marks a position in a chirp listing. Clients only ever see it
+// as an opaque string, so the encoding is free to change.
+type chirpCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// encode packs the cursor into a URL-safe token. Timestamps are stored with
+// microsecond precision, which is what Postgres keeps.
+func (c chirpCursor) encode() string {
+	raw := fmt.Sprintf("%d:%s", c.CreatedAt.UnixMicro(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeChirpCursor(s string) (chirpCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return chirpCursor{}, err
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return chirpCursor{}, errors.New("malformed cursor")
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return chirpCursor{}, err
+	}
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		return chirpCursor{}, err
+	}
+	return chirpCursor{
+		CreatedAt: time.UnixMicro(usec).UTC(),
+		ID:        chirpID,
+	}, nil
+}
+
+// encodeThreadCursor wraps the path of the last chirp on a thread page.
+func encodeThreadCursor(path string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(path))
+}
+
+func decodeThreadCursor(s string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) == 0 {
+		return "", errors.New("malformed cursor")
+	}
+	return string(raw), nil
+}
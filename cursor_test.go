@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// This test ensures a chirp cursor survives the round trip to microsecond
+// precision and rejects malformed tokens
+func TestChirpCursor(t *testing.T) {
+	id := uuid.MustParse("d0c6f1f4-5b1e-4d4e-9a51-3c0e3a1d9b42")
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 123456789, time.UTC)
+	raw := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name    string
+		cursor  string
+		want    chirpCursor
+		wantErr bool
+	}{
+		{
+			name:   "Round trip",
+			cursor: chirpCursor{CreatedAt: createdAt, ID: id}.encode(),
+			want:   chirpCursor{CreatedAt: createdAt.Truncate(time.Microsecond), ID: id},
+		},
+		{
+			name:   "Before the epoch",
+			cursor: chirpCursor{CreatedAt: time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC), ID: id}.encode(),
+			want:   chirpCursor{CreatedAt: time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC), ID: id},
+		},
+		{
+			name:    "Bad base64",
+			cursor:  "not base64!",
+			wantErr: true,
+		},
+		{
+			name:    "Missing separator",
+			cursor:  raw("1760788800000000" + id.String()),
+			wantErr: true,
+		},
+		{
+			name:    "Non-numeric micros",
+			cursor:  raw("yesterday:" + id.String()),
+			wantErr: true,
+		},
+		{
+			name:    "Bad UUID",
+			cursor:  raw("1760788800000000:not-a-uuid"),
+			wantErr: true,
+		},
+		{
+			name:    "Empty",
+			cursor:  "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeChirpCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeChirpCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.CreatedAt.Equal(tt.want.CreatedAt) || got.ID != tt.want.ID {
+				t.Errorf("decodeChirpCursor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// This test ensures a thread cursor gives back the path it was made from
+// and rejects tokens that can't be one
+func TestThreadCursor(t *testing.T) {
+	path := "20261018120000000001d0c6f1f4-5b1e-4d4e-9a51-3c0e3a1d9b42/20261018120500000002a7e9c1b0-0f7b-4f43-8a7c-6c2d8f1e0b33"
+
+	tests := []struct {
+		name    string
+		cursor  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "Round trip",
+			cursor: encodeThreadCursor(path),
+			want:   path,
+		},
+		{
+			name:    "Empty",
+			cursor:  base64.RawURLEncoding.EncodeToString(nil),
+			wantErr: true,
+		},
+		{
+			name:    "Bad base64",
+			cursor:  "not base64!",
+			wantErr: true,
+		},
+		{
+			name:    "Padded base64",
+			cursor:  base64.URLEncoding.EncodeToString([]byte("a")),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeThreadCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeThreadCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decodeThreadCursor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// This test ensures following X-Next-Cursor through a thread visits every
+// chirp once, in order, whatever the page size
+func TestThreadPaging(t *testing.T) {
+	// Paths sort in thread order, as GetChirpThread orders them.
+	paths := []string{"a", "a/b", "a/b/c", "a/d", "a/d/e", "a/d/f", "a/g"}
+
+	// fetch stands in for GetChirpThread: rows after the cursor's path, one
+	// more than a page.
+	fetch := func(after string, pageSize int) []string {
+		var rows []string
+		for _, path := range paths {
+			if path > after && len(rows) < pageSize+1 {
+				rows = append(rows, path)
+			}
+		}
+		return rows
+	}
+
+	for pageSize := 1; pageSize <= len(paths)+1; pageSize++ {
+		var seen []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > len(paths) {
+				t.Fatalf("pageSize %d: still paging after %d pages", pageSize, pages)
+			}
+			after := ""
+			if cursor != "" {
+				var err error
+				after, err = decodeThreadCursor(cursor)
+				if err != nil {
+					t.Fatalf("pageSize %d: decodeThreadCursor() error = %v", pageSize, err)
+				}
+			}
+			rows, more := trimPage(fetch(after, pageSize), pageSize)
+			if len(rows) > pageSize {
+				t.Fatalf("pageSize %d: got a page of %d", pageSize, len(rows))
+			}
+			seen = append(seen, rows...)
+			if !more {
+				break
+			}
+			cursor = encodeThreadCursor(rows[len(rows)-1])
+		}
+		if !slices.Equal(seen, paths) {
+			t.Errorf("pageSize %d: saw %s, want %s", pageSize, strings.Join(seen, " "), strings.Join(paths, " "))
+		}
+	}
+}
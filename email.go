@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/ItSpecOps/go-server/internal/mailer"
+)
+
+const (
+	// emailVerificationTTL is how long a verification link stays usable.
+	emailVerificationTTL = 24 * time.Hour
+	// passwordResetTTL is how long a password reset link stays usable.
+	passwordResetTTL = time.Hour
+	// magicLinkTTL is how long a sign-in link stays usable.
+	magicLinkTTL = 15 * time.Minute
+)
+
+// validEmail reports whether email is a bare address like
+// "user@example.com", without a display name or angle brackets.
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// sendVerificationEmail mails user a token proving they own their address.
+// With VERIFY_EMAIL_URL set the token is sent as a link to that page;
+// otherwise the raw token is sent for the client to post to
+// /api/users/verify.
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := cfg.keyring.MakeEmailVerificationToken(user.ID, user.Email, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	body := "Confirm your Chirpy email address with this verification token:\n\n" + token + "\n"
+	if cfg.verifyEmailURL != "" {
+		link := cfg.verifyEmailURL + "?token=" + url.QueryEscape(token)
+		body = "Confirm your Chirpy email address by opening this link:\n\n" + link + "\n"
+	}
+	body += "\nIt expires in 24 hours. If you didn't sign up for Chirpy, ignore this email.\n"
+
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email address",
+		Body:    body,
+	})
+}
+
+// sendPasswordResetEmail mails user their reset token, as a link to
+// RESET_PASSWORD_URL when that is set.
+func (cfg *apiConfig) sendPasswordResetEmail(ctx context.Context, user database.User, token string) error {
+	body := "Reset your Chirpy password with this token:\n\n" + token + "\n"
+	if cfg.resetURL != "" {
+		link := cfg.resetURL + "?token=" + url.QueryEscape(token)
+		body = "Reset your Chirpy password by opening this link:\n\n" + link + "\n"
+	}
+	body += "\nIt expires in an hour and works once. If you didn't ask to reset your password, ignore this email.\n"
+
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body:    body,
+	})
+}
+
+// sendMagicLinkEmail mails user a sign-in token, as a link to
+// MAGIC_LINK_URL when that is set.
+func (cfg *apiConfig) sendMagicLinkEmail(ctx context.Context, user database.User, token string) error {
+	body := "Sign in to Chirpy with this token:\n\n" + token + "\n"
+	if cfg.magicLinkURL != "" {
+		link := cfg.magicLinkURL + "?token=" + url.QueryEscape(token)
+		body = "Sign in to Chirpy by opening this link:\n\n" + link + "\n"
+	}
+	body += "\nIt expires in 15 minutes and works once. If you didn't ask to sign in, ignore this email.\n"
+
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Sign in to Chirpy",
+		Body:    body,
+	})
+}
+
+// middlewareRequireVerifiedEmail only lets through users who have verified
+// their email address. It must be wrapped by middlewareRequireAuth.
+func (cfg *apiConfig) middlewareRequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := requestPrincipal(r)
+		user, err := cfg.db.GetUserByID(r.Context(), principal.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+			return
+		}
+		if !user.EmailVerifiedAt.Valid {
+			respondWithError(w, http.StatusForbidden, "Verify your email address first", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+// This test ensures only a bare address is accepted, not a display name,
+// list or anything mail.ParseAddress would have to rewrite
+func TestValidEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		want  bool
+	}{
+		{name: "Plain", email: "user@example.com", want: true},
+		{name: "Dots and plus", email: "first.last+tag@mail.example.co", want: true},
+		{name: "Empty", email: "", want: false},
+		{name: "No at sign", email: "user.example.com", want: false},
+		{name: "No local part", email: "@example.com", want: false},
+		{name: "No domain", email: "user@", want: false},
+		{name: "Two at signs", email: "user@@example.com", want: false},
+		{name: "Display name", email: "User <user@example.com>", want: false},
+		{name: "Angle brackets", email: "<user@example.com>", want: false},
+		{name: "Surrounding spaces", email: " user@example.com ", want: false},
+		{name: "Space in local part", email: "us er@example.com", want: false},
+		{name: "List", email: "user@example.com, other@example.com", want: false},
+		{name: "Comment", email: "user@example.com (work)", want: false},
+		{name: "Newline", email: "user@example.com\nBcc: other@example.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validEmail(tt.email); got != tt.want {
+				t.Errorf("validEmail(%q) = %v, want %v", tt.email, got, tt.want)
+			}
+		})
+	}
+}
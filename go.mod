@@ -3,12 +3,13 @@ module github.com/ItSpecOps/go-server
 go 1.25.0
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.3.0
+	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
+	github.com/rivo/uniseg v0.4.7
+	golang.org/x/crypto v0.41.0
+	golang.org/x/text v0.28.0
 )
 
-require (
-	github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
-	golang.org/x/crypto v0.41.0 // indirect
-)
+require golang.org/x/sys v0.35.0 // indirect
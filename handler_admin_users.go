@@ -0,0 +1,160 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerAdminUsersUpdateRole changes a user's role. Lowering it also ends
+// every session they have, since their tokens carry the old role and its
+// scopes.
+func (cfg *apiConfig) handlerAdminUsersUpdateRole(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	role, err := auth.ParseRole(params.Role)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "role must be user, moderator or admin", err)
+		return
+	}
+
+	var user database.User
+	var sessionIDs []uuid.UUID
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		current, err := q.GetUserByID(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		user, err = q.UpdateUserRole(r.Context(), database.UpdateUserRoleParams{
+			ID:   userID,
+			Role: string(role),
+		})
+		if err != nil {
+			return err
+		}
+		if !role.AtLeast(auth.Role(current.Role)) {
+			sessionIDs, err = q.RevokeUserRefreshTokens(r.Context(), userID)
+		}
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update role", err)
+		return
+	}
+	cfg.revocations.endSessions(sessionIDs)
+
+	respondWithJSON(w, http.StatusOK, userFromDB(user))
+}
+
+// handlerAdminUsersUnlock clears a user's failed login count and any
+// lockout. Per-IP backoff is left alone.
+func (cfg *apiConfig) handlerAdminUsersUnlock(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	if err := cfg.db.ClearLoginAttempts(r.Context(), emailThrottleKey(user.Email)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unlock user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerAdminUsersRevokeSessions signs a user out everywhere: every
+// session is ended, which rejects its refresh and access tokens.
+func (cfg *apiConfig) handlerAdminUsersRevokeSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	_, err = cfg.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	sessionIDs, err := cfg.db.RevokeUserRefreshTokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	cfg.revocations.endSessions(sessionIDs)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerAdminTokensRevoke denylists a single access token, such as one that
+// turned up in a log, without touching the rest of its session.
+func (cfg *apiConfig) handlerAdminTokensRevoke(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	claims, err := cfg.keyring.ValidateJWTContext(r.Context(), params.Token, cfg.accessTokens)
+	if errors.Is(err, auth.ErrTokenRevoked) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Token isn't a valid access token", err)
+		return
+	}
+	if claims.ID == "" {
+		respondWithError(w, http.StatusBadRequest, "Token has no jti to revoke; revoke the user's sessions instead", nil)
+		return
+	}
+
+	if err := cfg.revocations.revokeToken(r.Context(), claims); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke token", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerAPIKeysCreate issues an API key for the caller. The key is only
+// returned in this response; we keep just its hash.
+func (cfg *apiConfig) handlerAPIKeysCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	type response struct {
+		APIKey
+		Key string `json:"key"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" || len(params.Name) > maxAPIKeyNameLength {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("name must be 1 to %d characters", maxAPIKeyNameLength), nil)
+		return
+	}
+	if len(params.Scopes) == 0 {
+		params.Scopes = []string{auth.ScopeChirpsRead}
+	}
+	principal, _ := requestPrincipal(r)
+	for _, scope := range params.Scopes {
+		if !slices.Contains(apiKeyGrantableScopes, scope) {
+			respondWithError(w, http.StatusBadRequest, "scope "+scope+" can't be granted to API keys", nil)
+			return
+		}
+		if !slices.Contains(principal.Role.Scopes(), scope) {
+			respondWithError(w, http.StatusForbidden, "Your role doesn't allow scope "+scope, nil)
+			return
+		}
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			respondWithError(w, http.StatusBadRequest, "expires_at must be in the future", nil)
+			return
+		}
+		expiresAt = sql.NullTime{Time: *params.ExpiresAt, Valid: true}
+	}
+
+	count, err := cfg.db.CountUserAPIKeys(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count API keys", err)
+		return
+	}
+	if count >= maxAPIKeysPerUser {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("You can have at most %d API keys; revoke one first", maxAPIKeysPerUser), nil)
+		return
+	}
+
+	key, keyID, err := auth.GenerateAPIKey()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate API key", err)
+		return
+	}
+	apiKey, err := cfg.db.CreateAPIKey(r.Context(), database.CreateAPIKeyParams{
+		UserID:    principal.UserID,
+		KeyID:     keyID,
+		KeyHash:   auth.HashAPIKey(key),
+		Name:      params.Name,
+		Scopes:    params.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save API key", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response{
+		APIKey: apiKeyFromDB(apiKey),
+		Key:    key,
+	})
+}
+
+// handlerAPIKeysList lists the caller's API keys that haven't been revoked.
+func (cfg *apiConfig) handlerAPIKeysList(w http.ResponseWriter, r *http.Request) {
+	principal, _ := requestPrincipal(r)
+
+	rows, err := cfg.db.ListUserAPIKeys(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list API keys", err)
+		return
+	}
+
+	keys := make([]APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, apiKeyFromDB(row))
+	}
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+// handlerAPIKeysDelete revokes one of the caller's API keys. It stops
+// working immediately.
+func (cfg *apiConfig) handlerAPIKeysDelete(w http.ResponseWriter, r *http.Request) {
+	keyID, err := uuid.Parse(r.PathValue("keyID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid API key ID", err)
+		return
+	}
+
+	principal, _ := requestPrincipal(r)
+	n, err := cfg.db.RevokeAPIKey(r.Context(), database.RevokeAPIKeyParams{
+		ID:     keyID,
+		UserID: principal.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke API key", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "API key not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
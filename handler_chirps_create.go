@@ -1,30 +1,22 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/ItSpecOps/go-server/internal/database"
-	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/moderation"
 	"github.com/google/uuid"
 )
 
-
 func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request) {
 	var req createChirpParams
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "missing or malformed JWT", err)
-		return
-	}
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "invalid or expired JWT", err)
-		return
-	}
+	principal, _ := requestPrincipal(r)
+	userID := principal.UserID
 
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&req); err != nil {
@@ -32,49 +24,71 @@ func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-
 	if userID == uuid.Nil {
 		respondWithError(w, http.StatusBadRequest, "invalid user_id", nil)
 		return
 	}
 
-	// Validate Chirp length
-	if len(req.Body) > 140 {
-		respondWithError(w, http.StatusBadRequest, "chirp body exceeds 140 characters", nil)
+	body, moderated, ok := cfg.checkChirpBody(w, req.Body)
+	if !ok {
 		return
 	}
+	cleaned := moderated.Text
 
-	// Profanity filter
-	profaneWords := []string{"kerfuffle", "sharbert", "fornax"}
-	words := strings.Split(req.Body, " ")
-	for i, word := range words {
-		for _, profane := range profaneWords {
-			if strings.EqualFold(word, profane) {
-				words[i] = "****"
-				break
-			}
+	// Replies join the conversation of the chirp they answer.
+	inReplyTo := uuid.NullUUID{}
+	root := uuid.NullUUID{}
+	if req.InReplyToID != nil {
+		parent, err := cfg.db.GetChirp(r.Context(), *req.InReplyToID)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusBadRequest, "The chirp you're replying to doesn't exist", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get the chirp you're replying to", err)
+			return
+		}
+		inReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		root = parent.RootID
+		if !root.Valid {
+			root = inReplyTo
 		}
 	}
 
-	cleaned := strings.Join(words, " ")
-
 	dbChirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleaned,
-		UserID: userID,
+		Body:        cleaned,
+		UserID:      userID,
+		InReplyToID: inReplyTo,
+		RootID:      root,
 	})
 	if err != nil {
 		fmt.Printf("CreateUser error: %v\n", err) // Log the actual error
 		http.Error(w, `{"error":"could not create chirp"}`, http.StatusInternalServerError)
 		return
 	}
-	resp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
-	}
+	cfg.flagChirp(r.Context(), dbChirp.ID, body, moderated.Matching(moderation.ActionFlag))
+	resp := chirpFromDB(dbChirp)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(resp)
-}
\ No newline at end of file
+}
+
+// checkChirpBody validates and moderates the body of a new or edited chirp.
+// body is the validated text as written, which flags quote; moderated.Text
+// is what to store. On failure it responds and returns false.
+func (cfg *apiConfig) checkChirpBody(w http.ResponseWriter, raw string) (body string, moderated moderation.Result, ok bool) {
+	body, err := cfg.chirpPolicy.Clean(raw)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Chirp "+err.Error(), err)
+		return "", moderation.Result{}, false
+	}
+
+	// Moderation rules may mask parts of the chirp, reject it, or flag it
+	// for review once it is saved.
+	moderated = cfg.moderation.Pipeline().Moderate(body)
+	if rejected := moderated.Matching(moderation.ActionReject); len(rejected) > 0 {
+		respondWithError(w, http.StatusUnprocessableEntity, "Chirp breaks the "+rejected[0].Rule+" rule", nil)
+		return "", moderation.Result{}, false
+	}
+	return body, moderated, true
+}
@@ -15,23 +15,16 @@ func (cfg *apiConfig) handlerChirpsDelete(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
-		return
-	}
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
-		return
-	}
+	principal, _ := requestPrincipal(r)
+	userID := principal.UserID
 
 	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Couldn't get chirp", err)
 		return
 	}
-	if dbChirp.UserID != userID {
+	// Moderators may remove anyone's chirps.
+	if dbChirp.UserID != userID && !principal.HasRole(auth.RoleModerator) {
 		respondWithError(w, http.StatusForbidden, "You can't delete this chirp", err)
 		return
 	}
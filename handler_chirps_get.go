@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/ItSpecOps/go-server/internal/database"
 	"github.com/google/uuid"
 )
 
+const (
+	defaultChirpPageSize = 20
+	maxChirpPageSize     = 100
+)
+
 func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, r *http.Request) {
 	chirpIDString := r.PathValue("chirpID")
 	chirpID, err := uuid.Parse(chirpIDString)
@@ -14,38 +25,162 @@ func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expandAuthor, err := parseChirpExpand(r.URL.Query().Get("expand"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
 	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Couldn't get chirp", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		UserID:    dbChirp.UserID,
-		Body:      dbChirp.Body,
-	})
+	chirps := []Chirp{chirpFromDB(dbChirp)}
+	if expandAuthor {
+		if err := cfg.embedChirpAuthors(r.Context(), chirps); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp author", err)
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps[0])
 }
 
 func (cfg *apiConfig) handlerChirpsRetrieve(w http.ResponseWriter, r *http.Request) {
-	dbChirps, err := cfg.db.GetChirps(r.Context())
+	query := r.URL.Query()
+
+	pageSize := defaultChirpPageSize
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxChirpPageSize {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxChirpPageSize), err)
+			return
+		}
+		pageSize = n
+	}
+
+	expandAuthor, err := parseChirpExpand(query.Get("expand"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	authorID := uuid.NullUUID{}
+	if author := query.Get("author_id"); author != "" {
+		id, err := uuid.Parse(author)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author ID", err)
+			return
+		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	afterCreatedAt := sql.NullTime{}
+	afterID := uuid.NullUUID{}
+	if cursor := query.Get("cursor"); cursor != "" {
+		c, err := decodeChirpCursor(cursor)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+		afterCreatedAt = sql.NullTime{Time: c.CreatedAt, Valid: true}
+		afterID = uuid.NullUUID{UUID: c.ID, Valid: true}
+	}
+
+	// Fetch one extra row so we know whether another page exists.
+	var dbChirps []database.Chirp
+	switch query.Get("sort") {
+	case "", "asc":
+		dbChirps, err = cfg.db.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+			AuthorID:       authorID,
+			AfterCreatedAt: afterCreatedAt,
+			AfterID:        afterID,
+			PageSize:       int32(pageSize + 1),
+		})
+	case "desc":
+		dbChirps, err = cfg.db.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AuthorID:       authorID,
+			AfterCreatedAt: afterCreatedAt,
+			AfterID:        afterID,
+			PageSize:       int32(pageSize + 1),
+		})
+	default:
+		respondWithError(w, http.StatusBadRequest, "sort must be asc or desc", nil)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
 		return
 	}
 
+	dbChirps, more := trimPage(dbChirps, pageSize)
 	chirps := []Chirp{}
 	for _, dbChirp := range dbChirps {
-		chirps = append(chirps, Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			UserID:    dbChirp.UserID,
-			Body:      dbChirp.Body,
-		})
+		chirps = append(chirps, chirpFromDB(dbChirp))
+	}
+	if expandAuthor {
+		if err := cfg.embedChirpAuthors(r.Context(), chirps); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp authors", err)
+			return
+		}
 	}
 
+	// The body stays a bare array, as it was before pagination, so the
+	// cursor for the next page goes in a header.
+	if more {
+		last := dbChirps[len(dbChirps)-1]
+		w.Header().Set("X-Next-Cursor", chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode())
+	}
 	respondWithJSON(w, http.StatusOK, chirps)
 }
+
+// parseChirpExpand reads the comma-separated expand query parameter.
+// "author" is the only expansion chirps support.
+func parseChirpExpand(expand string) (author bool, err error) {
+	if expand == "" {
+		return false, nil
+	}
+	for _, field := range strings.Split(expand, ",") {
+		if strings.TrimSpace(field) != "author" {
+			return false, fmt.Errorf("can't expand %q", field)
+		}
+		author = true
+	}
+	return author, nil
+}
+
+// embedChirpAuthors sets Author on each chirp, looking every author up
+// once. Chirps whose author can't be found or is pending deletion get a
+// null author.
+func (cfg *apiConfig) embedChirpAuthors(ctx context.Context, chirps []Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	seen := map[uuid.UUID]bool{}
+	ids := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if !seen[chirp.UserID] {
+			seen[chirp.UserID] = true
+			ids = append(ids, chirp.UserID)
+		}
+	}
+
+	users, err := cfg.db.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	profiles := make(map[uuid.UUID]Profile, len(users))
+	for _, user := range users {
+		profiles[user.ID] = profileFromDB(user)
+	}
+	for i := range chirps {
+		chirps[i].Author = chirpAuthor{expanded: true}
+		if profile, ok := profiles[chirps[i].UserID]; ok {
+			chirps[i].Author.profile = &profile
+		}
+	}
+	return nil
+}
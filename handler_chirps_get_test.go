@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+// This test ensures expand only accepts author, however it is repeated or
+// spaced, and that leaving it out expands nothing
+func TestParseChirpExpand(t *testing.T) {
+	tests := []struct {
+		name       string
+		expand     string
+		wantAuthor bool
+		wantErr    bool
+	}{
+		{name: "Empty", expand: "", wantAuthor: false},
+		{name: "Author", expand: "author", wantAuthor: true},
+		{name: "Spaced", expand: " author ", wantAuthor: true},
+		{name: "Duplicate", expand: "author,author", wantAuthor: true},
+		{name: "Duplicate with spaces", expand: "author, author", wantAuthor: true},
+		{name: "Unknown", expand: "likes", wantErr: true},
+		{name: "Unknown after author", expand: "author,likes", wantErr: true},
+		{name: "Wrong case", expand: "Author", wantErr: true},
+		{name: "Empty entry", expand: "author,", wantErr: true},
+		{name: "Only a comma", expand: ",", wantErr: true},
+		{name: "Only spaces", expand: "  ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			author, err := parseChirpExpand(tt.expand)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseChirpExpand(%q) error = %v, wantErr %v", tt.expand, err, tt.wantErr)
+			}
+			if author != tt.wantAuthor {
+				t.Errorf("parseChirpExpand(%q) = %v, want %v", tt.expand, author, tt.wantAuthor)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultThreadDepth    = 5
+	maxThreadDepth        = 20
+	defaultThreadPageSize = 50
+	maxThreadPageSize     = 200
+)
+
+// ThreadChirp is a chirp placed in a conversation. Depth counts the replies
+// between it and the chirp the thread was requested for.
+type ThreadChirp struct {
+	Chirp
+	Depth int `json:"depth"`
+}
+
+// handlerChirpsThread returns a chirp followed by the replies under it,
+// depth first: each chirp comes before its replies, and replies to the same
+// chirp are oldest first. Chirps deeper than the depth limit are left out;
+// a reply_count above zero on the last level shows where to ask for more.
+// Like the chirp list, the cursor for the next page is sent in the
+// X-Next-Cursor header.
+func (cfg *apiConfig) handlerChirpsThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+	query := r.URL.Query()
+
+	depth := defaultThreadDepth
+	if d := query.Get("depth"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 0 || n > maxThreadDepth {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("depth must be between 0 and %d", maxThreadDepth), err)
+			return
+		}
+		depth = n
+	}
+
+	pageSize := defaultThreadPageSize
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxThreadPageSize {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxThreadPageSize), err)
+			return
+		}
+		pageSize = n
+	}
+
+	expandAuthor, err := parseChirpExpand(query.Get("expand"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	afterPath := ""
+	cursor := query.Get("cursor")
+	if cursor != "" {
+		afterPath, err = decodeThreadCursor(cursor)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+	}
+
+	// Fetch one extra row so we know whether another page exists.
+	rows, err := cfg.db.GetChirpThread(r.Context(), database.GetChirpThreadParams{
+		ID:        chirpID,
+		MaxDepth:  int32(depth),
+		AfterPath: afterPath,
+		PageSize:  int32(pageSize + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get thread", err)
+		return
+	}
+	// The first page always starts with the chirp itself.
+	if len(rows) == 0 && cursor == "" {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+		return
+	}
+
+	rows, more := trimPage(rows, pageSize)
+	chirps := make([]Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, chirpFromDB(database.Chirp{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			Body:        row.Body,
+			UserID:      row.UserID,
+			EditedAt:    row.EditedAt,
+			InReplyToID: row.InReplyToID,
+			RootID:      row.RootID,
+			ReplyCount:  row.ReplyCount,
+		}))
+	}
+	if expandAuthor {
+		if err := cfg.embedChirpAuthors(r.Context(), chirps); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp authors", err)
+			return
+		}
+	}
+	thread := make([]ThreadChirp, 0, len(chirps))
+	for i, chirp := range chirps {
+		thread = append(thread, ThreadChirp{
+			Chirp: chirp,
+			Depth: int(rows[i].Depth),
+		})
+	}
+
+	if more {
+		w.Header().Set("X-Next-Cursor", encodeThreadCursor(rows[len(rows)-1].Path))
+	}
+	respondWithJSON(w, http.StatusOK, thread)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/ItSpecOps/go-server/internal/moderation"
+	"github.com/google/uuid"
+)
+
+// handlerChirpsUpdate lets the author change a chirp's body within the edit
+// window. The new body is checked like a new chirp's, and the old one is
+// kept as a revision.
+func (cfg *apiConfig) handlerChirpsUpdate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	principal, _ := requestPrincipal(r)
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+		return
+	}
+	// Unlike deleting, moderators can't edit other users' chirps.
+	if dbChirp.UserID != principal.UserID {
+		respondWithError(w, http.StatusForbidden, "You can't edit this chirp", nil)
+		return
+	}
+	editableAfter := time.Now().Add(-cfg.editWindow)
+	if !dbChirp.CreatedAt.After(editableAfter) {
+		respondWithError(w, http.StatusForbidden, "Chirps can only be edited for "+cfg.editWindow.String()+" after posting", nil)
+		return
+	}
+
+	body, moderated, ok := cfg.checkChirpBody(w, params.Body)
+	if !ok {
+		return
+	}
+	if moderated.Text == dbChirp.Body {
+		respondWithJSON(w, http.StatusOK, chirpFromDB(dbChirp))
+		return
+	}
+
+	updated, err := cfg.db.EditChirp(r.Context(), database.EditChirpParams{
+		Body:          moderated.Text,
+		ID:            chirpID,
+		UserID:        principal.UserID,
+		EditableAfter: editableAfter,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Deleted, or the window closed, since we looked.
+		respondWithError(w, http.StatusConflict, "Chirp can no longer be edited", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't edit chirp", err)
+		return
+	}
+	cfg.flagChirp(r.Context(), updated.ID, body, moderated.Matching(moderation.ActionFlag))
+
+	respondWithJSON(w, http.StatusOK, chirpFromDB(updated))
+}
+
+// handlerChirpsRevisions lists the bodies a chirp had before each edit,
+// oldest first. Like chirps themselves, revisions are public.
+func (cfg *apiConfig) handlerChirpsRevisions(w http.ResponseWriter, r *http.Request) {
+	type revision struct {
+		Body       string    `json:"body"`
+		WrittenAt  time.Time `json:"written_at"`
+		ReplacedAt time.Time `json:"replaced_at"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+	if _, err := cfg.db.GetChirp(r.Context(), chirpID); errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+		return
+	}
+
+	rows, err := cfg.db.ListChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list revisions", err)
+		return
+	}
+
+	revisions := make([]revision, 0, len(rows))
+	for _, row := range rows {
+		revisions = append(revisions, revision{
+			Body:       row.Body,
+			WrittenAt:  row.WrittenAt,
+			ReplacedAt: row.ReplacedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, revisions)
+}
@@ -1,12 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"log"
 	"net/http"
-	"time"
 
-	"github.com/ItSpecOps/go-server/internal/auth"
 	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
 )
 
 func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
@@ -14,11 +15,6 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 		Email    string `json:"email"`
 	}
-	type response struct {
-		User
-		Token string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
-	}
 
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
@@ -28,48 +24,158 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
+	user, ok := cfg.checkLoginPassword(w, r, params.Email, params.Password)
+	if !ok {
+		return
+	}
+	if user.DeleteAfter.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is scheduled for deletion; restore it to log in", nil)
+		return
+	}
+
+	// The plaintext is only available now, so this is our one chance to
+	// move the hash to the current algorithm and parameters.
+	if cfg.passwords.NeedsRehash(user.HashedPassword) {
+		cfg.rehashPassword(r.Context(), user.ID, params.Password)
+	}
+
+	cfg.continueLogin(w, r, user)
+}
+
+// continueLogin finishes a login once the first factor has been checked:
+// users with two-factor enabled get a challenge token for
+// /api/login/2fa, everyone else gets their tokens.
+func (cfg *apiConfig) continueLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	if user.TotpEnabledAt.Valid {
+		challenge, err := cfg.keyring.MakeChallengeToken(user.ID, twoFactorChallengeTTL)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Could not generate challenge token", err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, twoFactorChallenge{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		})
+		return
+	}
+
+	cfg.completeLogin(w, r, user)
+}
+
+// checkLoginPassword checks an email and password behind the login
+// throttle. On failure it responds and returns false.
+func (cfg *apiConfig) checkLoginPassword(w http.ResponseWriter, r *http.Request, email, password string) (database.User, bool) {
+	keys := []throttledKey{
+		{key: emailThrottleKey(email), throttle: cfg.loginThrottles.email},
+		{key: ipThrottleKey(cfg.clientIP(r)), throttle: cfg.loginThrottles.ip},
+	}
+	wait, err := cfg.beginLoginAttempt(r.Context(), keys...)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check login attempts", err)
+		return database.User{}, false
+	}
+	if wait > 0 {
+		respondLoginBlocked(w, wait)
+		return database.User{}, false
+	}
+
+	user, err := cfg.db.GetUserByEmail(r.Context(), email)
+	if err == nil {
+		err = cfg.passwords.Check(password, user.HashedPassword)
+	}
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		return database.User{}, false
+	}
+	cfg.forgiveLoginAttempt(r.Context(), keys...)
+	return user, true
+}
+
+// handlerLoginTwoFactor is the second step of a login for users with two-
+// factor enabled: it trades the challenge token from /api/login plus a TOTP
+// or recovery code for real tokens.
+func (cfg *apiConfig) handlerLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
 		return
 	}
 
-	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	claims, err := cfg.keyring.ValidateJWT(params.ChallengeToken, challengeTokenOptions(cfg.keyring))
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge token", err)
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge token", err)
+		return
+	}
+	if !cfg.verifySecondFactor(w, r, user, params.Code, params.RecoveryCode) {
 		return
 	}
 
-	// Default expiration if 1 hour (3600 seconds)
-	expiresIn := time.Hour
-	
-	accessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, expiresIn)
+	cfg.completeLogin(w, r, user)
+}
+
+// twoFactorChallenge is what /api/login returns instead of tokens when the
+// user still has to pass a second factor.
+type twoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+// completeLogin issues tokens for user once every factor has been checked.
+func (cfg *apiConfig) completeLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	type response struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	emailKey := emailThrottleKey(user.Email)
+	if err := cfg.db.ClearLoginAttempts(r.Context(), emailKey); err != nil {
+		log.Printf("Couldn't clear failed logins for %s: %s", emailKey, err)
+	}
+
+	// Each login starts a new session, identified by its token family.
+	sessionID := uuid.New()
+	accessToken, err := cfg.makeAccessToken(user, sessionID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Could not generate access token", err)
 		return
 	}
 
-	refreshToken, err := auth.MakeRefreshToken()
-
-	_, err = cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(24 * time.Hour * 60),
-	})
+	refreshToken, err := cfg.createRefreshToken(r, cfg.db, user.ID, sessionID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Could not generate refresh token", err)
 		return
 	}
 
-
 	respondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:        user.ID,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		},
-		Token: accessToken,
+		User:         userFromDB(user),
+		Token:        accessToken,
 		RefreshToken: refreshToken,
 	})
 }
+
+func (cfg *apiConfig) rehashPassword(ctx context.Context, userID uuid.UUID, password string) {
+	hashedPassword, err := cfg.passwords.Hash(password)
+	if err != nil {
+		log.Printf("Couldn't rehash password for user %s: %s", userID, err)
+		return
+	}
+	err = cfg.db.UpdateUserPasswordHash(ctx, database.UpdateUserPasswordHashParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		log.Printf("Couldn't store rehashed password for user %s: %s", userID, err)
+	}
+}
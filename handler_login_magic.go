@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+)
+
+const (
+	// magicLinkWindow and magicLinkMaxPerWindow limit how many sign-in
+	// emails one account can be sent, so the endpoint can't be used to
+	// flood someone's inbox.
+	magicLinkWindow       = 15 * time.Minute
+	magicLinkMaxPerWindow = 3
+)
+
+// handlerLoginMagic mails a single-use sign-in link. Like
+// handlerPasswordForgot it answers 202 whether or not the address belongs
+// to an account, and does the work after responding.
+func (cfg *apiConfig) handlerLoginMagic(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), time.Minute)
+	go func() {
+		defer cancel()
+		cfg.startMagicLink(ctx, params.Email)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// startMagicLink issues a sign-in token for the account with email, if
+// there is one that can log in, and mails it. Failures are only logged.
+func (cfg *apiConfig) startMagicLink(ctx context.Context, email string) {
+	user, err := cfg.db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Printf("Couldn't look up user for magic link: %s", err)
+		return
+	}
+	if user.DeleteAfter.Valid {
+		return
+	}
+
+	recent, err := cfg.db.CountRecentMagicLinkTokens(ctx, database.CountRecentMagicLinkTokensParams{
+		UserID: user.ID,
+		Since:  time.Now().Add(-magicLinkWindow),
+	})
+	if err != nil {
+		log.Printf("Couldn't count magic links for user %s: %s", user.ID, err)
+		return
+	}
+	if recent >= magicLinkMaxPerWindow {
+		log.Printf("Not sending magic link to user %s: too many recent requests", user.ID)
+		return
+	}
+
+	// Only the token's hash is stored; see auth.HashToken.
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("Couldn't generate magic link token: %s", err)
+		return
+	}
+	err = cfg.db.CreateMagicLinkToken(ctx, database.CreateMagicLinkTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(magicLinkTTL),
+	})
+	if err != nil {
+		log.Printf("Couldn't save magic link token for user %s: %s", user.ID, err)
+		return
+	}
+
+	if err := cfg.sendMagicLinkEmail(ctx, user, token); err != nil {
+		log.Printf("Couldn't send magic link email to user %s: %s", user.ID, err)
+	}
+}
+
+// handlerLoginMagicRedeem trades a token from handlerLoginMagic for the same
+// response as a password login, including the two-factor challenge for
+// users who have it enabled.
+func (cfg *apiConfig) handlerLoginMagicRedeem(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	magicToken, err := cfg.db.UseMagicLinkToken(r.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired sign-in link", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't use sign-in link", err)
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), magicToken.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+	if user.DeleteAfter.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is scheduled for deletion; restore it to log in", nil)
+		return
+	}
+
+	if err := cfg.db.InvalidateMagicLinkTokens(r.Context(), user.ID); err != nil {
+		log.Printf("Couldn't invalidate other magic links for user %s: %s", user.ID, err)
+	}
+	// Following the link proves control of the mailbox.
+	if !user.EmailVerifiedAt.Valid {
+		verified, err := cfg.db.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+			ID:    user.ID,
+			Email: user.Email,
+		})
+		if err != nil {
+			log.Printf("Couldn't verify email for user %s: %s", user.ID, err)
+		} else {
+			user = verified
+		}
+	}
+
+	cfg.continueLogin(w, r, user)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/ItSpecOps/go-server/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultFlagPageSize = 50
+	maxFlagPageSize     = 200
+)
+
+// ModerationFlag is a chirp waiting for review because it matched a rule
+// with the flag action.
+type ModerationFlag struct {
+	ID        uuid.UUID `json:"id"`
+	Rule      string    `json:"rule"`
+	Excerpt   string    `json:"excerpt"`
+	CreatedAt time.Time `json:"created_at"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	ChirpBody string    `json:"chirp_body"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+// flagChirp queues a new chirp for review, once per flagging match. body is
+// the chirp as written, before masking, so moderators see what was posted.
+// Failures are only logged; the chirp has already been created.
+func (cfg *apiConfig) flagChirp(ctx context.Context, chirpID uuid.UUID, body string, matches []moderation.Match) {
+	for _, m := range matches {
+		err := cfg.db.CreateModerationFlag(ctx, database.CreateModerationFlagParams{
+			ChirpID: chirpID,
+			Rule:    m.Rule,
+			Excerpt: body[m.Start:m.End],
+		})
+		if err != nil {
+			log.Printf("Couldn't flag chirp %s for rule %s: %s", chirpID, m.Rule, err)
+		}
+	}
+}
+
+// handlerModerationFlagsList lists unresolved flags, oldest first.
+func (cfg *apiConfig) handlerModerationFlagsList(w http.ResponseWriter, r *http.Request) {
+	pageSize := defaultFlagPageSize
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxFlagPageSize {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxFlagPageSize), err)
+			return
+		}
+		pageSize = n
+	}
+
+	rows, err := cfg.db.ListOpenModerationFlags(r.Context(), int32(pageSize))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list flags", err)
+		return
+	}
+
+	flags := make([]ModerationFlag, 0, len(rows))
+	for _, row := range rows {
+		flags = append(flags, ModerationFlag{
+			ID:        row.ID,
+			Rule:      row.Rule,
+			Excerpt:   row.Excerpt,
+			CreatedAt: row.CreatedAt,
+			ChirpID:   row.ChirpID,
+			ChirpBody: row.ChirpBody,
+			UserID:    row.ChirpUserID,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, flags)
+}
+
+// handlerModerationFlagsResolve closes a flag once a moderator has looked at
+// the chirp. Chirps that should go are removed with DELETE /api/chirps/{id},
+// which also clears their flags.
+func (cfg *apiConfig) handlerModerationFlagsResolve(w http.ResponseWriter, r *http.Request) {
+	flagID, err := uuid.Parse(r.PathValue("flagID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid flag ID", err)
+		return
+	}
+
+	principal, _ := requestPrincipal(r)
+	n, err := cfg.db.ResolveModerationFlag(r.Context(), database.ResolveModerationFlagParams{
+		ID:         flagID,
+		ResolvedBy: uuid.NullUUID{UUID: principal.UserID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve flag", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Flag not found or already resolved", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+// authorizeParams are the RFC 6749 authorization request parameters, which
+// the consent page passes through from the client's link.
+type authorizeParams struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// authorizeRequest is an authorization request that passed checkAuthorize.
+type authorizeRequest struct {
+	client        database.OauthClient
+	redirectURI   string
+	scope         string
+	state         string
+	codeChallenge string
+}
+
+// checkAuthorize validates an authorization request. PKCE with S256 is
+// required of every client. On failure it responds and returns false.
+func (cfg *apiConfig) checkAuthorize(w http.ResponseWriter, r *http.Request, params authorizeParams) (authorizeRequest, bool) {
+	clientID, err := uuid.Parse(params.ClientID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid client_id", err)
+		return authorizeRequest{}, false
+	}
+	client, err := cfg.db.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Unknown client", err)
+		return authorizeRequest{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get client", err)
+		return authorizeRequest{}, false
+	}
+	// Only exact matches, so a client can't be made to send codes elsewhere.
+	if !slices.Contains(client.RedirectUris, params.RedirectURI) {
+		respondWithError(w, http.StatusBadRequest, "redirect_uri isn't registered for this client", nil)
+		return authorizeRequest{}, false
+	}
+
+	if params.ResponseType != "code" {
+		respondWithError(w, http.StatusBadRequest, "response_type must be code", nil)
+		return authorizeRequest{}, false
+	}
+	if params.CodeChallengeMethod != auth.PKCEMethodS256 || !auth.ValidPKCEChallenge(params.CodeChallenge) {
+		respondWithError(w, http.StatusBadRequest, "An S256 code_challenge is required", nil)
+		return authorizeRequest{}, false
+	}
+	scope, err := parseOAuthScope(params.Scope)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return authorizeRequest{}, false
+	}
+
+	return authorizeRequest{
+		client:        client,
+		redirectURI:   params.RedirectURI,
+		scope:         scope,
+		state:         params.State,
+		codeChallenge: params.CodeChallenge,
+	}, true
+}
+
+// handlerOAuthAuthorizeGet checks an authorization request for the consent
+// page and describes what the client is asking for.
+func (cfg *apiConfig) handlerOAuthAuthorizeGet(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		ClientID    uuid.UUID `json:"client_id"`
+		ClientName  string    `json:"client_name"`
+		RedirectURI string    `json:"redirect_uri"`
+		Scopes      []string  `json:"scopes"`
+	}
+
+	query := r.URL.Query()
+	req, ok := cfg.checkAuthorize(w, r, authorizeParams{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	})
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		ClientID:    req.client.ID,
+		ClientName:  req.client.Name,
+		RedirectURI: req.redirectURI,
+		Scopes:      strings.Fields(req.scope),
+	})
+}
+
+// handlerOAuthAuthorize records the user's decision on an authorization
+// request and returns where to send the browser next: back to the client
+// with a code, or with access_denied.
+func (cfg *apiConfig) handlerOAuthAuthorize(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		authorizeParams
+		Approve bool `json:"approve"`
+	}
+	type response struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	req, ok := cfg.checkAuthorize(w, r, params.authorizeParams)
+	if !ok {
+		return
+	}
+
+	values := url.Values{}
+	if req.state != "" {
+		values.Set("state", req.state)
+	}
+
+	if params.Approve {
+		// Only the code's hash is stored; see auth.HashToken.
+		code, err := auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't generate authorization code", err)
+			return
+		}
+		principal, _ := requestPrincipal(r)
+		err = cfg.db.CreateOAuthAuthorizationCode(r.Context(), database.CreateOAuthAuthorizationCodeParams{
+			CodeHash:      auth.HashToken(code),
+			ClientID:      req.client.ID,
+			UserID:        principal.UserID,
+			RedirectUri:   req.redirectURI,
+			Scope:         req.scope,
+			CodeChallenge: req.codeChallenge,
+			FamilyID:      uuid.New(),
+			ExpiresAt:     time.Now().Add(oauthCodeTTL),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't save authorization code", err)
+			return
+		}
+		values.Set("code", code)
+	} else {
+		values.Set("error", "access_denied")
+	}
+
+	redirect, _ := url.Parse(req.redirectURI)
+	query := redirect.Query()
+	for key, value := range values {
+		query[key] = value
+	}
+	redirect.RawQuery = query.Encode()
+
+	respondWithJSON(w, http.StatusOK, response{
+		RedirectTo: redirect.String(),
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerOAuthClientsCreate registers a third-party app owned by the caller.
+// Confidential clients get a secret, returned only in this response.
+func (cfg *apiConfig) handlerOAuthClientsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+	type response struct {
+		OAuthClient
+		ClientSecret string `json:"client_secret,omitempty"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" || len(params.Name) > maxOAuthClientNameLength {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("name must be 1 to %d characters", maxOAuthClientNameLength), nil)
+		return
+	}
+	if len(params.RedirectURIs) == 0 || len(params.RedirectURIs) > maxOAuthRedirectURIs {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("redirect_uris must list 1 to %d URIs", maxOAuthRedirectURIs), nil)
+		return
+	}
+	for _, uri := range params.RedirectURIs {
+		if !validRedirectURI(uri) {
+			respondWithError(w, http.StatusBadRequest, "Redirect URIs must be https, or http on a loopback address, without a fragment: "+uri, nil)
+			return
+		}
+	}
+
+	// Only the secret's hash is stored; see auth.HashToken.
+	var secret string
+	secretHash := sql.NullString{}
+	if params.Confidential {
+		var err error
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't generate client secret", err)
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	principal, _ := requestPrincipal(r)
+	client, err := cfg.db.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		OwnerID:      principal.UserID,
+		Name:         params.Name,
+		SecretHash:   secretHash,
+		RedirectUris: params.RedirectURIs,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create client", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response{
+		OAuthClient:  oauthClientFromDB(client),
+		ClientSecret: secret,
+	})
+}
+
+// handlerOAuthClientsList lists the apps the caller has registered.
+func (cfg *apiConfig) handlerOAuthClientsList(w http.ResponseWriter, r *http.Request) {
+	principal, _ := requestPrincipal(r)
+
+	rows, err := cfg.db.ListUserOAuthClients(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list clients", err)
+		return
+	}
+
+	clients := make([]OAuthClient, 0, len(rows))
+	for _, row := range rows {
+		clients = append(clients, oauthClientFromDB(row))
+	}
+	respondWithJSON(w, http.StatusOK, clients)
+}
+
+// handlerOAuthClientsDelete deletes one of the caller's apps along with its
+// codes, and ends every session it holds so the access tokens issued to it
+// stop working too.
+func (cfg *apiConfig) handlerOAuthClientsDelete(w http.ResponseWriter, r *http.Request) {
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid client ID", err)
+		return
+	}
+
+	principal, _ := requestPrincipal(r)
+	var families []uuid.UUID
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		_, err := q.LockOAuthClient(r.Context(), database.LockOAuthClientParams{
+			ID:      clientID,
+			OwnerID: principal.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		families, err = q.RevokeOAuthClientRefreshTokens(r.Context(), uuid.NullUUID{UUID: clientID, Valid: true})
+		if err != nil {
+			return err
+		}
+		return q.DeleteOAuthClient(r.Context(), clientID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Client not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete client", err)
+		return
+	}
+	for _, familyID := range families {
+		cfg.revocations.endSession(familyID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
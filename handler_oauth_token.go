@@ -0,0 +1,278 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerOAuthToken is the RFC 6749 token endpoint. It accepts the
+// authorization_code grant, with PKCE, and the refresh_token grant.
+func (cfg *apiConfig) handlerOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_request", "Couldn't parse form", err)
+		return
+	}
+	client, ok := cfg.requireOAuthClient(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		cfg.oauthAuthorizationCodeGrant(w, r, client)
+	case "refresh_token":
+		cfg.oauthRefreshTokenGrant(w, r, client)
+	default:
+		respondOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token", nil)
+	}
+}
+
+// oauthAuthorizationCodeGrant exchanges a code from handlerOAuthAuthorize
+// for tokens. A code works once; redeeming it again revokes whatever it was
+// exchanged for, since one of the two requests came from an attacker. The
+// code is only used up once the client, redirect_uri and code_verifier all
+// check out, so whoever merely sees a code can't burn it.
+func (cfg *apiConfig) oauthAuthorizationCodeGrant(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	codeHash := auth.HashToken(r.PostForm.Get("code"))
+
+	code, err := cfg.db.GetOAuthAuthorizationCode(r.Context(), codeHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code", err)
+		return
+	}
+	if err != nil {
+		respondOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't get authorization code", err)
+		return
+	}
+
+	if code.ClientID != client.ID || code.RedirectUri != r.PostForm.Get("redirect_uri") {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "Authorization code was issued to another client or redirect_uri", nil)
+		return
+	}
+	if err := auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge); err != nil {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier doesn't match code_challenge", err)
+		return
+	}
+	if code.UsedAt.Valid {
+		cfg.revokeAuthorizationCodeGrant(r, code)
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code", nil)
+		return
+	}
+
+	n, err := cfg.db.UseOAuthAuthorizationCode(r.Context(), database.UseOAuthAuthorizationCodeParams{
+		CodeHash: codeHash,
+		ClientID: client.ID,
+	})
+	if err != nil {
+		respondOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't use authorization code", err)
+		return
+	}
+	if n == 0 {
+		// Expired, or another valid redemption got there first.
+		if !code.ExpiresAt.Before(time.Now()) {
+			cfg.revokeAuthorizationCodeGrant(r, code)
+		}
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code", nil)
+		return
+	}
+
+	user, ok := cfg.oauthGrantUser(w, r, code.UserID)
+	if !ok {
+		return
+	}
+	tokens, err := cfg.makeOAuthTokens(r, cfg.db, user, client, code.Scope, code.FamilyID)
+	if err != nil {
+		respondOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't issue tokens", err)
+		return
+	}
+	respondOAuthTokens(w, tokens)
+}
+
+// revokeAuthorizationCodeGrant revokes the tokens issued for a code that
+// was validly redeemed a second time, as RFC 6749 section 4.1.2 recommends.
+func (cfg *apiConfig) revokeAuthorizationCodeGrant(r *http.Request, code database.OauthAuthorizationCode) {
+	log.Printf("Authorization code reuse detected for client %s, revoking token family %s", code.ClientID, code.FamilyID)
+	if err := cfg.db.RevokeRefreshTokenFamily(r.Context(), code.FamilyID); err != nil {
+		log.Printf("Couldn't revoke token family %s: %s", code.FamilyID, err)
+		return
+	}
+	cfg.revocations.endSession(code.FamilyID)
+}
+
+// oauthRefreshTokenGrant rotates a refresh token issued to client, like
+// handlerRefresh does for first-party tokens. The client may ask for a
+// narrower scope than the user granted, never a wider one.
+func (cfg *apiConfig) oauthRefreshTokenGrant(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	tokenHash := auth.HashToken(r.PostForm.Get("refresh_token"))
+
+	// Look before rotating, so a bad scope request or a user who can't be
+	// issued tokens doesn't burn the token.
+	current, err := cfg.db.GetRefreshToken(r.Context(), tokenHash)
+	if err != nil || current.ClientID.UUID != client.ID {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired refresh token", err)
+		return
+	}
+	scope := current.Scope.String
+	if requested := r.PostForm.Get("scope"); requested != "" {
+		if !scopeSubset(requested, scope) {
+			respondOAuthError(w, http.StatusBadRequest, "invalid_scope", "scope exceeds what the user granted", nil)
+			return
+		}
+		scope = requested
+	}
+
+	user, ok := cfg.oauthGrantUser(w, r, current.UserID)
+	if !ok {
+		return
+	}
+
+	// As in handlerRefresh, the old token is revoked and its replacement
+	// saved in one transaction.
+	var tokens oauthTokens
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		oldToken, err := q.RotateOAuthRefreshToken(r.Context(), database.RotateOAuthRefreshTokenParams{
+			TokenHash: tokenHash,
+			ClientID:  uuid.NullUUID{UUID: client.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		tokens, err = cfg.makeOAuthTokens(r, q, user, client, scope, oldToken.FamilyID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.detectRefreshTokenReuse(r.Context(), r.PostForm.Get("refresh_token"))
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired refresh token", err)
+		return
+	}
+	if err != nil {
+		respondOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't rotate refresh token", err)
+		return
+	}
+	respondOAuthTokens(w, tokens)
+}
+
+// oauthGrantUser looks up the user a grant belongs to. Accounts pending
+// deletion can't be used by clients any more than they can log in. On
+// failure it responds and returns false.
+func (cfg *apiConfig) oauthGrantUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.User, bool) {
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't get user", err)
+		return database.User{}, false
+	}
+	if user.DeleteAfter.Valid {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "Account is scheduled for deletion", nil)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// handlerOAuthRevoke is the RFC 7009 revocation endpoint. A client can
+// revoke its own refresh tokens, which ends the whole grant, or its own
+// access tokens. Anything else is ignored, and the answer is always 200 so
+// clients can't probe for valid tokens.
+func (cfg *apiConfig) handlerOAuthRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_request", "Couldn't parse form", err)
+		return
+	}
+	client, ok := cfg.requireOAuthClient(w, r)
+	if !ok {
+		return
+	}
+	token := r.PostForm.Get("token")
+
+	refreshToken, err := cfg.db.GetRefreshToken(r.Context(), auth.HashToken(token))
+	if err == nil && refreshToken.ClientID.UUID == client.ID {
+		if err := cfg.db.RevokeRefreshTokenFamily(r.Context(), refreshToken.FamilyID); err != nil {
+			respondOAuthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "Couldn't revoke token", err)
+			return
+		}
+		cfg.revocations.endSession(refreshToken.FamilyID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	claims, err := cfg.keyring.ValidateJWTContext(r.Context(), token, cfg.accessTokens)
+	if err == nil && claims.ClientID == client.ID.String() && claims.ID != "" {
+		if err := cfg.revocations.revokeToken(r.Context(), claims); err != nil {
+			respondOAuthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "Couldn't revoke token", err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handlerOAuthIntrospect is the RFC 7662 introspection endpoint. A client
+// only learns about tokens issued to it; every other token reads as
+// inactive.
+func (cfg *apiConfig) handlerOAuthIntrospect(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Subject   string `json:"sub,omitempty"`
+		Username  string `json:"username,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		ExpiresAt int64  `json:"exp,omitempty"`
+		IssuedAt  int64  `json:"iat,omitempty"`
+	}
+
+	if err := r.ParseForm(); err != nil {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_request", "Couldn't parse form", err)
+		return
+	}
+	client, ok := cfg.requireOAuthClient(w, r)
+	if !ok {
+		return
+	}
+	token := r.PostForm.Get("token")
+
+	resp := response{}
+	var userID uuid.UUID
+	refreshToken, err := cfg.db.GetRefreshToken(r.Context(), auth.HashToken(token))
+	if err == nil {
+		live := !refreshToken.RevokedAt.Valid && refreshToken.ExpiresAt.After(time.Now())
+		if live && refreshToken.ClientID.UUID == client.ID {
+			resp = response{
+				Active:    true,
+				Scope:     refreshToken.Scope.String,
+				ExpiresAt: refreshToken.ExpiresAt.Unix(),
+				IssuedAt:  refreshToken.CreatedAt.Unix(),
+			}
+			userID = refreshToken.UserID
+		}
+	} else if claims, err := cfg.keyring.ValidateJWTContext(r.Context(), token, cfg.accessTokens); err == nil && claims.ClientID == client.ID.String() {
+		resp = response{
+			Active:    true,
+			Scope:     claims.Scope,
+			TokenType: "Bearer",
+			ExpiresAt: claims.ExpiresAt.Unix(),
+			IssuedAt:  claims.IssuedAt.Unix(),
+		}
+		userID = claims.UserID
+	}
+
+	if resp.Active {
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			respondOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't get user", err)
+			return
+		}
+		resp.ClientID = client.ID.String()
+		resp.Subject = user.ID.String()
+		resp.Username = user.Handle
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, resp)
+}
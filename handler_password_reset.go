@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerPasswordForgot mails a password reset link. It answers 202 whether
+// or not the address belongs to an account, and does the work after
+// responding so the timing doesn't tell either.
+func (cfg *apiConfig) handlerPasswordForgot(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), time.Minute)
+	go func() {
+		defer cancel()
+		cfg.startPasswordReset(ctx, params.Email)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// passwordResetWindow and passwordResetMaxPerWindow limit how many reset
+// emails one account can be sent, for the same reason as the magic link
+// limits.
+const (
+	passwordResetWindow       = 15 * time.Minute
+	passwordResetMaxPerWindow = 3
+)
+
+// startPasswordReset issues a reset token for the account with email, if
+// there is one, and mails it. Failures are only logged.
+func (cfg *apiConfig) startPasswordReset(ctx context.Context, email string) {
+	user, err := cfg.db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Printf("Couldn't look up user for password reset: %s", err)
+		return
+	}
+
+	recent, err := cfg.db.CountRecentPasswordResetTokens(ctx, database.CountRecentPasswordResetTokensParams{
+		UserID: user.ID,
+		Since:  time.Now().Add(-passwordResetWindow),
+	})
+	if err != nil {
+		log.Printf("Couldn't count password resets for user %s: %s", user.ID, err)
+		return
+	}
+	if recent >= passwordResetMaxPerWindow {
+		log.Printf("Not sending password reset to user %s: too many recent requests", user.ID)
+		return
+	}
+
+	// Only the token's hash is stored; see auth.HashToken.
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("Couldn't generate password reset token: %s", err)
+		return
+	}
+	err = cfg.db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		log.Printf("Couldn't save password reset token for user %s: %s", user.ID, err)
+		return
+	}
+
+	if err := cfg.sendPasswordResetEmail(ctx, user, token); err != nil {
+		log.Printf("Couldn't send password reset email to user %s: %s", user.ID, err)
+	}
+}
+
+// handlerPasswordReset sets a new password using a token from
+// handlerPasswordForgot, then signs the user out everywhere.
+func (cfg *apiConfig) handlerPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	tokenHash := auth.HashToken(params.Token)
+
+	// Look before using the token, so a password the policy rejects doesn't
+	// burn it.
+	resetToken, err := cfg.db.GetPasswordResetToken(r.Context(), tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired reset token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get reset token", err)
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), resetToken.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+	if !cfg.checkPasswordPolicy(w, params.Password, user.Email) {
+		return
+	}
+	hashedPassword, err := cfg.passwords.Hash(params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
+		return
+	}
+
+	// The token is only used up if the new password takes effect with it.
+	var sessionIDs []uuid.UUID
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if _, err := q.UsePasswordResetToken(r.Context(), tokenHash); err != nil {
+			return err
+		}
+		err := q.UpdateUserPasswordHash(r.Context(), database.UpdateUserPasswordHashParams{
+			ID:             user.ID,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+		sessionIDs, err = q.RevokeUserRefreshTokens(r.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+		return q.InvalidatePasswordResetTokens(r.Context(), user.ID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired reset token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+	cfg.revocations.endSessions(sessionIDs)
+	// Proving control of the mailbox is enough to lift a lockout.
+	if err := cfg.db.ClearLoginAttempts(r.Context(), emailThrottleKey(user.Email)); err != nil {
+		log.Printf("Couldn't clear failed logins for user %s: %s", user.ID, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
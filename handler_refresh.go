@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
-	"time"
 
 	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
 )
 
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 	type response struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	refreshToken, err := auth.GetBearerToken(r.Header)
@@ -18,25 +23,58 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := cfg.db.GetUserFromRefreshToken(r.Context(), refreshToken)
-	if err != nil {
+	// Revoking the presented token and checking it was still live happen in
+	// one statement, so of two concurrent refreshes only one finds the token
+	// live; the other is treated as reuse. Its replacement is issued in the
+	// same transaction, so a failure leaves the old token working.
+	resp := response{}
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		oldToken, err := q.RotateRefreshToken(r.Context(), auth.HashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+
+		// Look the user up again so role changes apply from the next refresh.
+		user, err := q.GetUserByID(r.Context(), oldToken.UserID)
+		if err != nil {
+			return err
+		}
+		resp.Token, err = cfg.makeAccessToken(user, oldToken.FamilyID)
+		if err != nil {
+			return err
+		}
+		resp.RefreshToken, err = cfg.createRefreshToken(r, q, user.ID, oldToken.FamilyID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.detectRefreshTokenReuse(r.Context(), refreshToken)
 		respondWithError(w, http.StatusUnauthorized, "Couldn't get user for refresh token", err)
 		return
 	}
-
-	accessToken, err := auth.MakeJWT(
-		user.ID,
-		cfg.jwtSecret,
-		time.Hour,
-	)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't rotate refresh token", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, response{
-		Token: accessToken,
-	})
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// detectRefreshTokenReuse is called when a refresh token could not be
+// rotated. If the token had already been rotated, someone is replaying an
+// old token: either the legitimate client or an attacker holds a stolen
+// copy, and we can't tell which, so the whole family is revoked.
+func (cfg *apiConfig) detectRefreshTokenReuse(ctx context.Context, refreshToken string) {
+	dbToken, err := cfg.db.GetRefreshToken(ctx, auth.HashToken(refreshToken))
+	if err != nil || !dbToken.RotatedAt.Valid {
+		return
+	}
+
+	log.Printf("Refresh token reuse detected for user %s, revoking token family %s (possible token theft)", dbToken.UserID, dbToken.FamilyID)
+	if err := cfg.db.RevokeRefreshTokenFamily(ctx, dbToken.FamilyID); err != nil {
+		log.Printf("Couldn't revoke token family %s: %s", dbToken.FamilyID, err)
+		return
+	}
+	cfg.revocations.endSession(dbToken.FamilyID)
 }
 
 func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
@@ -46,11 +84,12 @@ func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = cfg.db.RevokeRefreshToken(r.Context(), refreshToken)
+	dbToken, err := cfg.db.RevokeRefreshToken(r.Context(), auth.HashToken(refreshToken))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke session", err)
 		return
 	}
+	cfg.revocations.endSession(dbToken.FamilyID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
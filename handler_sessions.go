@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerSessionsList(w http.ResponseWriter, r *http.Request) {
+	principal, _ := requestPrincipal(r)
+
+	rows, err := cfg.db.ListUserSessions(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list sessions", err)
+		return
+	}
+
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, sessionFromDB(row, principal.SessionID))
+	}
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+// handlerSessionsDelete ends one of the caller's sessions. Its refresh token
+// stops working at once, and so do access tokens issued to it.
+func (cfg *apiConfig) handlerSessionsDelete(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid session ID", err)
+		return
+	}
+
+	principal, _ := requestPrincipal(r)
+	revoked, err := cfg.db.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		FamilyID: sessionID,
+		UserID:   principal.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke session", err)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find session", nil)
+		return
+	}
+	cfg.revocations.endSession(sessionID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerSessionsDeleteAll logs the caller out everywhere, including the
+// session the request came from.
+func (cfg *apiConfig) handlerSessionsDeleteAll(w http.ResponseWriter, r *http.Request) {
+	principal, _ := requestPrincipal(r)
+
+	sessionIDs, err := cfg.db.RevokeUserRefreshTokens(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	cfg.revocations.endSessions(sessionIDs)
+
+	w.WriteHeader(http.StatusNoContent)
+}
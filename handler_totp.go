@@ -0,0 +1,177 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+)
+
+// handlerTOTPEnroll starts TOTP enrollment by generating a new secret. Two-
+// factor stays off until a code from it is confirmed, so calling this again
+// simply replaces a pending secret. The current password is required, so a
+// stolen access token can't be used to enroll someone else's authenticator.
+func (cfg *apiConfig) handlerTOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		CurrentPassword string `json:"current_password"`
+	}
+	type response struct {
+		Secret string `json:"secret"`
+		URI    string `json:"otpauth_uri"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	principal, _ := requestPrincipal(r)
+	user, err := cfg.db.GetUserByID(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if !cfg.checkCurrentPassword(w, r, user, params.CurrentPassword) {
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate TOTP secret", err)
+		return
+	}
+	err = cfg.db.SetUserTOTPSecret(r.Context(), database.SetUserTOTPSecretParams{
+		ID:         user.ID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save TOTP secret", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Secret: secret,
+		URI:    auth.TOTPURI(secret, totpIssuer, user.Email),
+	})
+}
+
+// handlerTOTPConfirm turns two-factor on once the user proves their app
+// produces the right codes, and hands out recovery codes. This is the only
+// time the recovery codes are shown.
+func (cfg *apiConfig) handlerTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	principal, _ := requestPrincipal(r)
+	user, err := cfg.db.GetUserByID(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, http.StatusBadRequest, "Start two-factor enrollment first", nil)
+		return
+	}
+
+	step, err := auth.ValidateTOTP(user.TotpSecret.String, params.Code, time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Incorrect two-factor code", err)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate recovery codes", err)
+		return
+	}
+	// Replace the recovery codes and enable two-factor together, so it is
+	// never on without the codes the user is about to be shown.
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if err := q.DeleteRecoveryCodes(r.Context(), user.ID); err != nil {
+			return err
+		}
+		for _, code := range codes {
+			err := q.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+				UserID:   user.ID,
+				CodeHash: auth.HashRecoveryCode(code),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return q.EnableUserTOTP(r.Context(), database.EnableUserTOTPParams{
+			ID:               user.ID,
+			TotpLastUsedStep: sql.NullInt64{Int64: step, Valid: true},
+		})
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't enable two-factor authentication", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		RecoveryCodes: codes,
+	})
+}
+
+// handlerTOTPDisable turns two-factor off. An access token alone isn't
+// enough: the caller must also pass a current code or a recovery code.
+func (cfg *apiConfig) handlerTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	principal, _ := requestPrincipal(r)
+	user, err := cfg.db.GetUserByID(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication isn't enabled", nil)
+		return
+	}
+	if !cfg.verifySecondFactor(w, r, user, params.Code, params.RecoveryCode) {
+		return
+	}
+
+	if err := cfg.db.DisableUserTOTP(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't disable two-factor authentication", err)
+		return
+	}
+	if err := cfg.db.DeleteRecoveryCodes(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete recovery codes", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,21 +3,42 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 
-	"github.com/ItSpecOps/go-server/internal/auth"
 	"github.com/ItSpecOps/go-server/internal/database"
 )
 
-
 func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request) {
 	var req createUserParams
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
 		http.Error(w, `{"error":"invalid request"}`, http.StatusBadRequest)
 		return
 	}
+	if !validEmail(req.Email) {
+		respondWithError(w, http.StatusBadRequest, "Invalid email address", nil)
+		return
+	}
+
+	handle := strings.TrimPrefix(req.Handle, "@")
+	if handle == "" {
+		var err error
+		handle, err = defaultHandle()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't generate handle", err)
+			return
+		}
+	} else if err := validateHandle(handle); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
 
-	hashedPassword, err := auth.HashPassword(req.Password)
+	if !cfg.checkPasswordPolicy(w, req.Password, req.Email) {
+		return
+	}
+
+	hashedPassword, err := cfg.passwords.Hash(req.Password)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "could not hash password", err)
 		return
@@ -26,19 +47,24 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 	dbUser, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          req.Email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	})
+	if handleTaken(err) {
+		respondWithError(w, http.StatusConflict, "Handle is already taken", err)
+		return
+	}
 	if err != nil {
 		fmt.Printf("CreateUser error: %v\n", err) // Log the actual error
 		http.Error(w, `{"error":"could not create user"}`, http.StatusInternalServerError)
 		return
 	}
-	resp := User{
-		ID:        dbUser.ID,
-		CreatedAt: dbUser.CreatedAt,
-		UpdatedAt: dbUser.UpdatedAt,
-		Email:     dbUser.Email,
+	// The account exists either way; a lost email can be resent.
+	if err := cfg.sendVerificationEmail(r.Context(), dbUser); err != nil {
+		log.Printf("Couldn't send verification email to user %s: %s", dbUser.ID, err)
 	}
+
+	resp := userFromDB(dbUser)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(resp)
-}
\ No newline at end of file
+}
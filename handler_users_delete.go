@@ -0,0 +1,86 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+)
+
+// handlerUsersDeleteMe schedules the caller's account for deletion after
+// the grace period and signs them out everywhere. Until then the account
+// can be restored with handlerUsersRestore.
+func (cfg *apiConfig) handlerUsersDeleteMe(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		CurrentPassword string `json:"current_password"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	principal, _ := requestPrincipal(r)
+	user, err := cfg.db.GetUserByID(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+	if !cfg.checkCurrentPassword(w, r, user, params.CurrentPassword) {
+		return
+	}
+
+	user, err = cfg.db.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams{
+		ID:          user.ID,
+		DeleteAfter: sql.NullTime{Time: time.Now().Add(cfg.deletionGrace), Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't schedule account deletion", err)
+		return
+	}
+
+	sessionIDs, err := cfg.db.RevokeUserRefreshTokens(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	cfg.revocations.endSessions(sessionIDs)
+
+	respondWithJSON(w, http.StatusAccepted, userFromDB(user))
+}
+
+// handlerUsersRestore cancels a pending account deletion. The account's
+// sessions were ended when deletion was requested, so it takes the email
+// and password rather than a token.
+func (cfg *apiConfig) handlerUsersRestore(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, ok := cfg.checkLoginPassword(w, r, params.Email, params.Password)
+	if !ok {
+		return
+	}
+	if !user.DeleteAfter.Valid {
+		respondWithError(w, http.StatusConflict, "Account isn't scheduled for deletion", nil)
+		return
+	}
+
+	user, err := cfg.db.CancelUserDeletion(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't restore account", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, userFromDB(user))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// handlerUsersExport returns everything we hold about the caller as a ZIP
+// of JSON files: profile.json, chirps.json and sessions.json. Secrets such
+// as password and TOTP hashes are left out.
+func (cfg *apiConfig) handlerUsersExport(w http.ResponseWriter, r *http.Request) {
+	principal, _ := requestPrincipal(r)
+
+	user, err := cfg.db.GetUserByID(r.Context(), principal.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+	dbChirps, err := cfg.db.ListUserChirps(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list chirps", err)
+		return
+	}
+	rows, err := cfg.db.ListUserSessions(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list sessions", err)
+		return
+	}
+
+	chirps := make([]Chirp, 0, len(dbChirps))
+	for _, dbChirp := range dbChirps {
+		chirps = append(chirps, chirpFromDB(dbChirp))
+	}
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, sessionFromDB(row, principal.SessionID))
+	}
+
+	// Build the archive in memory so a failure can still be reported as an
+	// error response rather than a truncated download.
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", userFromDB(user)},
+		{"chirps.json", chirps},
+		{"sessions.json", sessions},
+	}
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't build export", err)
+			return
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't build export", err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't build export", err)
+		return
+	}
+
+	filename := fmt.Sprintf("chirpy-export-%s.zip", time.Now().UTC().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+)
+
+// handlerUsersProfileGet returns the public profile for a handle, with or
+// without its leading "@". Handles match regardless of case.
+func (cfg *apiConfig) handlerUsersProfileGet(w http.ResponseWriter, r *http.Request) {
+	handle := strings.TrimPrefix(r.PathValue("handle"), "@")
+
+	user, err := cfg.db.GetUserByHandle(r.Context(), handle)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, profileFromDB(user))
+}
+
+// handlerUsersProfileUpdate changes the caller's public profile. Only the
+// fields present in the body change; an empty string clears a field other
+// than the handle.
+func (cfg *apiConfig) handlerUsersProfileUpdate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	update := database.UpdateUserProfileParams{}
+	if params.Handle != nil {
+		handle := strings.TrimPrefix(*params.Handle, "@")
+		if err := validateHandle(handle); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		update.Handle = sql.NullString{String: handle, Valid: true}
+	}
+	if params.DisplayName != nil {
+		displayName, err := cleanProfileText("display_name", *params.DisplayName, displayNamePolicy)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		update.DisplayName = sql.NullString{String: displayName, Valid: true}
+	}
+	if params.Bio != nil {
+		bio, err := cleanProfileText("bio", *params.Bio, bioPolicy)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		update.Bio = sql.NullString{String: bio, Valid: true}
+	}
+	if params.AvatarURL != nil {
+		if err := validateAvatarURL(*params.AvatarURL); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		update.AvatarUrl = sql.NullString{String: *params.AvatarURL, Valid: true}
+	}
+
+	principal, _ := requestPrincipal(r)
+	update.ID = principal.UserID
+	user, err := cfg.db.UpdateUserProfile(r.Context(), update)
+	if handleTaken(err) {
+		respondWithError(w, http.StatusConflict, "Handle is already taken", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update profile", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, userFromDB(user))
+}
@@ -2,62 +2,163 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/ItSpecOps/go-server/internal/auth"
 	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// handlerUsersUpdate changes the caller's email and/or password. Only the
+// fields present in the body change, and either change needs the current
+// password.
 func (cfg *apiConfig) handlerUsersUpdate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
 	}
 	type response struct {
 		User
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	principal, _ := requestPrincipal(r)
+	userID := principal.UserID
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
 		return
 	}
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+	if params.Email == nil && params.Password == nil {
+		respondWithError(w, http.StatusBadRequest, "Nothing to update", nil)
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	err = decoder.Decode(&params)
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+	if !cfg.checkCurrentPassword(w, r, user, params.CurrentPassword) {
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(params.Password)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
+	// Validate everything before changing anything.
+	emailChanged := params.Email != nil && *params.Email != user.Email
+	if emailChanged && !validEmail(*params.Email) {
+		respondWithError(w, http.StatusBadRequest, "Invalid email address", nil)
 		return
 	}
+	var hashedPassword string
+	if params.Password != nil {
+		email := user.Email
+		if emailChanged {
+			email = *params.Email
+		}
+		if !cfg.checkPasswordPolicy(w, *params.Password, email) {
+			return
+		}
+		hashedPassword, err = cfg.passwords.Hash(*params.Password)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
+			return
+		}
+	}
 
-	user, err := cfg.db.UpdateUser(r.Context(), database.UpdateUserParams{
-		ID:             userID,
-		Email:          params.Email,
-		HashedPassword: hashedPassword,
+	// Every change is made in one transaction, so a failure leaves the
+	// account as it was.
+	var sessionIDs []uuid.UUID
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if emailChanged {
+			user, err = q.UpdateUserEmail(r.Context(), database.UpdateUserEmailParams{
+				ID:    userID,
+				Email: *params.Email,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if params.Password != nil {
+			err := q.UpdateUserPasswordHash(r.Context(), database.UpdateUserPasswordHashParams{
+				ID:             userID,
+				HashedPassword: hashedPassword,
+			})
+			if err != nil {
+				return err
+			}
+
+			// A new password ends every session, this one included, which
+			// rejects their access tokens too.
+			sessionIDs, err = q.RevokeUserRefreshTokens(r.Context(), userID)
+			if err != nil {
+				return err
+			}
+			user, err = q.GetUserByID(r.Context(), userID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		respondWithError(w, http.StatusConflict, "Email address is already in use", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't update user", err)
 		return
 	}
 
+	cfg.revocations.endSessions(sessionIDs)
+	if emailChanged {
+		// UpdateUserEmail cleared the verification.
+		if err := cfg.sendVerificationEmail(r.Context(), user); err != nil {
+			log.Printf("Couldn't send verification email to user %s: %s", user.ID, err)
+		}
+	}
+
 	respondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:        user.ID,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-			Email:     user.Email,
-		},
+		User: userFromDB(user),
 	})
 }
+
+// checkCurrentPassword confirms the caller knows user's password before a
+// sensitive change, so a stolen access token alone can't take over the
+// account. Wrong guesses count towards the login lockout. On failure it
+// responds and returns false.
+func (cfg *apiConfig) checkCurrentPassword(w http.ResponseWriter, r *http.Request, user database.User, password string) bool {
+	if password == "" {
+		respondWithError(w, http.StatusBadRequest, "current_password is required", nil)
+		return false
+	}
+
+	key := throttledKey{key: emailThrottleKey(user.Email), throttle: cfg.loginThrottles.email}
+	wait, err := cfg.beginLoginAttempt(r.Context(), key)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check login attempts", err)
+		return false
+	}
+	if wait > 0 {
+		respondLoginBlocked(w, wait)
+		return false
+	}
+
+	err = cfg.passwords.Check(password, user.HashedPassword)
+	if errors.Is(err, auth.ErrPasswordMismatch) {
+		respondWithError(w, http.StatusUnauthorized, "Current password is incorrect", err)
+		return false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check password", err)
+		return false
+	}
+	cfg.forgiveLoginAttempt(r.Context(), key)
+	return true
+}
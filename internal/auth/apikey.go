@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+)
+
+// APIKeyPrefix marks a string as a Chirpy API key, so leaked keys are easy
+// to spot in logs and by secret scanners.
+const APIKeyPrefix = "chirpy_"
+
+// ErrMalformedAPIKey is returned for strings that can't be an API key.
+var ErrMalformedAPIKey = errors.New("malformed API key")
+
+// apiKeyPattern matches "chirpy_<id>_<secret>": a 64-bit hex ID, stored in
+// the clear to look the key up, and a 256-bit hex secret. The ID is wide
+// enough that random IDs won't collide however many keys are issued.
+var apiKeyPattern = regexp.MustCompile(`^chirpy_([0-9a-f]{16})_[0-9a-f]{64}$`)
+
+// GenerateAPIKey returns a new random API key and its lookup ID.
+func GenerateAPIKey() (key, id string, err error) {
+	b := make([]byte, 8+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(b[:8])
+	return APIKeyPrefix + id + "_" + hex.EncodeToString(b[8:]), id, nil
+}
+
+// ParseAPIKey checks the format of key and returns its lookup ID.
+func ParseAPIKey(key string) (id string, err error) {
+	m := apiKeyPattern.FindStringSubmatch(key)
+	if m == nil {
+		return "", ErrMalformedAPIKey
+	}
+	return m[1], nil
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 digest of key. As with refresh
+// tokens, a plain hash is enough because keys are random.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
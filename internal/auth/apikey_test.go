@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// This test ensures generated keys parse back to their ID and are unique
+func TestGenerateAPIKey(t *testing.T) {
+	key, id, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+	got, err := ParseAPIKey(key)
+	if err != nil {
+		t.Fatalf("ParseAPIKey() error = %v", err)
+	}
+	if got != id {
+		t.Errorf("ParseAPIKey() = %q, want %q", got, id)
+	}
+
+	other, _, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+	if other == key {
+		t.Errorf("GenerateAPIKey() returned the same key twice")
+	}
+}
+
+// This test ensures only well-formed keys are accepted
+func TestParseAPIKey(t *testing.T) {
+	secret := strings.Repeat("a", 64)
+	tests := []struct {
+		name    string
+		key     string
+		wantID  string
+		wantErr bool
+	}{
+		{name: "Valid key", key: "chirpy_0123456789abcdef_" + secret, wantID: "0123456789abcdef"},
+		{name: "Wrong prefix", key: "chirp_0123456789abcdef_" + secret, wantErr: true},
+		{name: "Short ID", key: "chirpy_0123456789abcde_" + secret, wantErr: true},
+		{name: "Short secret", key: "chirpy_0123456789abcdef_" + secret[1:], wantErr: true},
+		{name: "Uppercase hex", key: "chirpy_0123456789ABCDEF_" + secret, wantErr: true},
+		{name: "Empty", key: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := ParseAPIKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAPIKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if id != tt.wantID {
+				t.Errorf("ParseAPIKey() = %q, want %q", id, tt.wantID)
+			}
+		})
+	}
+}
+
+// This test ensures the Authorization header is split into a known scheme and credentials
+func TestGetAuthorization(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantScheme string
+		wantCreds  string
+		wantErr    bool
+	}{
+		{name: "Bearer", header: "Bearer abc", wantScheme: SchemeBearer, wantCreds: "abc"},
+		{name: "API key", header: "ApiKey chirpy_x", wantScheme: SchemeAPIKey, wantCreds: "chirpy_x"},
+		{name: "Lowercase scheme", header: "bearer abc", wantScheme: SchemeBearer, wantCreds: "abc"},
+		{name: "Unknown scheme", header: "Basic abc", wantScheme: "Basic", wantCreds: "abc"},
+		{name: "Missing", header: "", wantErr: true},
+		{name: "No credentials", header: "Bearer", wantErr: true},
+		{name: "Empty credentials", header: "Bearer ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.header != "" {
+				headers.Set("Authorization", tt.header)
+			}
+			scheme, creds, err := GetAuthorization(headers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetAuthorization() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if scheme != tt.wantScheme || creds != tt.wantCreds {
+				t.Errorf("GetAuthorization() = %q, %q, want %q, %q", scheme, creds, tt.wantScheme, tt.wantCreds)
+			}
+		})
+	}
+
+	headers := http.Header{}
+	headers.Set("Authorization", "ApiKey chirpy_x")
+	if _, err := GetBearerToken(headers); err == nil {
+		t.Errorf("GetBearerToken() accepted an API key")
+	}
+}
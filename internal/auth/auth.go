@@ -2,77 +2,82 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"time"
 	"fmt"
 	"net/http"
+	"strings"
 
-	"golang.org/x/crypto/bcrypt"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// HashPassword hashes password with argon2id using DefaultArgon2Params.
 func HashPassword(password string) (string, error) {
-	// Implement password hashing logic here, e.g., using bcrypt
-	hashed_password_bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashed_password_bytes), nil
+	return PasswordHasher{Params: DefaultArgon2Params}.Hash(password)
 }
 
+// CheckPasswordHash verifies password against an argon2id or bcrypt hash.
 func CheckPasswordHash(password, hash string) error {
-	// Implement password hash comparison logic here, e.g., using bcrypt
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	return PasswordHasher{Params: DefaultArgon2Params}.Check(password, hash)
 }
 
+// MakeJWT issues an HS256 access token signed with tokenSecret. Servers with
+// more than one key should use a Keyring instead.
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	// Create the JWT claims, which includes the user ID and expiry time
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		Subject:   userID.String(),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-	})
-	// Sign the token with the secret
-	tokenString, err := token.SignedString([]byte(tokenSecret))
-	if err != nil {
-		return "", err
-	}
-	return tokenString, nil
+	return NewKeyring(NewHMACKey("", []byte(tokenSecret))).MakeJWT(userID, expiresIn)
 }
 
-
+// ValidateJWT verifies an HS256 access token signed with tokenSecret and
+// issued by us.
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	// Parse the token
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
+	claims, err := NewKeyring(NewHMACKey("", []byte(tokenSecret))).ValidateJWT(tokenString, ValidatorOptions{
+		Algorithms: []string{jwt.SigningMethodHS256.Alg()},
+		Issuer:     Issuer,
 	})
 	if err != nil {
 		return uuid.Nil, err
 	}
-	// Validate the token and extract claims
-	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
-		userID, err := uuid.Parse(claims.Subject)
-		if err != nil {
-			return uuid.Nil, err
+	return claims.UserID, nil
+}
+
+// Authorization schemes accepted in the Authorization header.
+const (
+	SchemeBearer = "Bearer"
+	SchemeAPIKey = "ApiKey"
+)
+
+// GetAuthorization splits the Authorization header into its scheme and
+// credentials. Schemes are case-insensitive (RFC 9110), so the scheme is
+// returned as one of our constants when it matches one.
+func GetAuthorization(headers http.Header) (scheme, credentials string, err error) {
+	authHeader := headers.Get("Authorization")
+	if authHeader == "" {
+		return "", "", fmt.Errorf("authorization header missing")
+	}
+	scheme, credentials, ok := strings.Cut(authHeader, " ")
+	if !ok || scheme == "" || credentials == "" {
+		return "", "", fmt.Errorf("invalid authorization header format")
+	}
+	for _, known := range []string{SchemeBearer, SchemeAPIKey} {
+		if strings.EqualFold(scheme, known) {
+			scheme = known
 		}
-		return userID, nil
-	} else {
-		return uuid.Nil, fmt.Errorf("invalid token")
 	}
+	return scheme, credentials, nil
 }
 
+// GetBearerToken returns the token from an "Authorization: Bearer" header.
 func GetBearerToken(headers http.Header) (string, error) {
-	authHeader := headers.Get("Authorization")
-	if authHeader == "" {
-		return "", fmt.Errorf("authorization header missing")
+	scheme, token, err := GetAuthorization(headers)
+	if err != nil {
+		return "", err
 	}
-	const prefix = "Bearer "
-	if len(authHeader) <= len(prefix) || authHeader[:len(prefix)] != prefix {
+	if scheme != SchemeBearer {
 		return "", fmt.Errorf("invalid authorization header format")
 	}
-	return authHeader[len(prefix):], nil
+	return token, nil
 }
 
 // MakeRefreshToken generates a random, hex-encoded 256-bit string
@@ -84,4 +89,14 @@ func MakeRefreshToken() (string, error) {
 		return "", err
 	}
 	return hex.EncodeToString(randomBytes), nil
+}
+
+// HashToken returns the hex-encoded SHA-256 digest of a secret token made
+// by MakeRefreshToken: refresh tokens, but also password reset and sign-in
+// tokens, OAuth authorization codes and client secrets. Only the digest is
+// stored, so a leaked database can't be used to redeem them. A plain hash is
+// sufficient because the tokens are random.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
\ No newline at end of file
@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
 	"time"
 
@@ -177,3 +181,217 @@ func TestValidateJWT_MalformedToken(t *testing.T) {
 		t.Fatalf("validated malformed token, expected an error")
 	}
 }
+
+
+// This test ensures refresh tokens hash deterministically and never to themselves
+func TestHashToken(t *testing.T) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("failed to make refresh token: %v", err)
+	}
+
+	hash := HashToken(token)
+	if hash == token {
+		t.Fatalf("hash equals the plaintext token")
+	}
+	if got := HashToken(token); got != hash {
+		t.Errorf("hash is not deterministic: got %q, want %q", got, hash)
+	}
+
+	other, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("failed to make refresh token: %v", err)
+	}
+	if HashToken(other) == hash {
+		t.Errorf("different tokens produced the same hash")
+	}
+}
+
+
+// This test ensures ValidateJWT only accepts tokens matching the validator options
+func TestValidateJWTOptions(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	signingKey, err := NewSigningKey(rsaKey)
+	if err != nil {
+		t.Fatalf("failed to create signing key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	// The keyring signs with RS256 but still holds a legacy HS256 secret.
+	legacySecret := []byte("my-test-secret")
+	keyring := NewKeyring(signingKey)
+	keyring.AddVerificationKey(NewHMACKey("", legacySecret), time.Time{})
+
+	strict := ValidatorOptions{
+		Algorithms:     []string{"RS256"},
+		Issuer:         Issuer,
+		Audiences:      []string{AccessTokenAudience},
+		Leeway:         time.Minute,
+		RequiredClaims: []string{"sub", "exp", "iat"},
+	}
+	withHS256 := strict
+	withHS256.Algorithms = []string{"RS256", "HS256"}
+	withJTI := strict
+	withJTI.RequiredClaims = []string{"sub", "exp", "iat", "jti"}
+	withSID := strict
+	withSID.RequiredClaims = []string{"sub", "exp", "iat", "sid"}
+
+	now := time.Now()
+	claims := func(edit func(*jwt.RegisteredClaims)) jwt.RegisteredClaims {
+		c := jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   uuid.New().String(),
+			Audience:  jwt.ClaimStrings{AccessTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		}
+		if edit != nil {
+			edit(&c)
+		}
+		return c
+	}
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, c jwt.RegisteredClaims) string {
+		token := jwt.NewWithClaims(method, c)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		tokenString, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return tokenString
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		opts    ValidatorOptions
+		wantErr bool
+	}{
+		{
+			name:  "Valid RS256 token",
+			token: sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(nil)),
+			opts:  strict,
+		},
+		{
+			name:    "Unsigned token with alg none",
+			token:   sign(jwt.SigningMethodNone, signingKey.ID, jwt.UnsafeAllowNoneSignatureType, claims(nil)),
+			opts:    withHS256,
+			wantErr: true,
+		},
+		{
+			name:    "HS256 token keyed with the RSA public key",
+			token:   sign(jwt.SigningMethodHS256, signingKey.ID, publicPEM, claims(nil)),
+			opts:    withHS256,
+			wantErr: true,
+		},
+		{
+			name:    "HS256 token when only RS256 is allowed",
+			token:   sign(jwt.SigningMethodHS256, "", legacySecret, claims(nil)),
+			opts:    strict,
+			wantErr: true,
+		},
+		{
+			name:  "HS256 token when HS256 is allowed",
+			token: sign(jwt.SigningMethodHS256, "", legacySecret, claims(nil)),
+			opts:  withHS256,
+		},
+		{
+			name:    "RS256 token naming the HS256 key",
+			token:   sign(jwt.SigningMethodRS256, "", rsaKey, claims(nil)),
+			opts:    withHS256,
+			wantErr: true,
+		},
+		{
+			name: "Wrong issuer",
+			token: sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(func(c *jwt.RegisteredClaims) {
+				c.Issuer = "not-chirpy"
+			})),
+			opts:    strict,
+			wantErr: true,
+		},
+		{
+			name: "Missing issuer",
+			token: sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(func(c *jwt.RegisteredClaims) {
+				c.Issuer = ""
+			})),
+			opts:    strict,
+			wantErr: true,
+		},
+		{
+			name: "Wrong audience",
+			token: sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(func(c *jwt.RegisteredClaims) {
+				c.Audience = jwt.ClaimStrings{"some-other-service"}
+			})),
+			opts:    strict,
+			wantErr: true,
+		},
+		{
+			name:    "Missing required jti",
+			token:   sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(nil)),
+			opts:    withJTI,
+			wantErr: true,
+		},
+		{
+			name:    "Missing required sid",
+			token:   sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(nil)),
+			opts:    withSID,
+			wantErr: true,
+		},
+		{
+			name: "Expired within leeway",
+			token: sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(func(c *jwt.RegisteredClaims) {
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(-30 * time.Second))
+			})),
+			opts: strict,
+		},
+		{
+			name: "Expired beyond leeway",
+			token: sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(func(c *jwt.RegisteredClaims) {
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(-2 * time.Minute))
+			})),
+			opts:    strict,
+			wantErr: true,
+		},
+		{
+			name: "Issued in the future",
+			token: sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(func(c *jwt.RegisteredClaims) {
+				c.IssuedAt = jwt.NewNumericDate(now.Add(5 * time.Minute))
+			})),
+			opts:    strict,
+			wantErr: true,
+		},
+		{
+			name: "Missing expiry",
+			token: sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(func(c *jwt.RegisteredClaims) {
+				c.ExpiresAt = nil
+			})),
+			opts:    strict,
+			wantErr: true,
+		},
+		{
+			name: "Subject is not a user ID",
+			token: sign(jwt.SigningMethodRS256, signingKey.ID, rsaKey, claims(func(c *jwt.RegisteredClaims) {
+				c.Subject = "admin"
+			})),
+			opts:    strict,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := keyring.ValidateJWT(tt.token, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
\ No newline at end of file
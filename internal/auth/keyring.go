@@ -0,0 +1,433 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// Issuer is the iss claim on every token this server signs.
+	Issuer = "chirpy"
+	// AccessTokenAudience is the aud claim on access tokens for our API.
+	AccessTokenAudience = "chirpy-api"
+	// TwoFactorAudience is the aud claim on login challenge tokens, which
+	// are only good for completing a two-factor login.
+	TwoFactorAudience = "chirpy-2fa"
+	// EmailVerificationAudience is the aud claim on email verification
+	// tokens.
+	EmailVerificationAudience = "chirpy-verify-email"
+)
+
+// SigningKey is a single key the server can sign or verify tokens with.
+// Symmetric keys (HS256) sign and verify with the same secret and are never
+// published; asymmetric keys publish their public half through the JWKS.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey wraps a shared secret as an HS256 key. Tokens signed with a key
+// whose ID is empty carry no kid header, which is how tokens minted before
+// key IDs existed are recognised.
+func NewHMACKey(id string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// NewSigningKey wraps an asymmetric private key. The algorithm follows from
+// the key type (RSA: RS256, ECDSA: ES256/ES384/ES512 by curve, Ed25519:
+// EdDSA) and the kid is the key's RFC 7638 thumbprint.
+func NewSigningKey(private crypto.Signer) (*SigningKey, error) {
+	key := &SigningKey{signKey: private, verifyKey: private.Public()}
+
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA keys must be at least 2048 bits, got %d", k.N.BitLen())
+		}
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			key.Method = jwt.SigningMethodES256
+		case elliptic.P384():
+			key.Method = jwt.SigningMethodES384
+		case elliptic.P521():
+			key.Method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+		}
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+
+	jwk, err := key.publicJWK()
+	if err != nil {
+		return nil, err
+	}
+	key.ID, err = jwk.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ParsePrivateKeyPEM reads a PKCS#8, PKCS#1 (RSA) or SEC 1 (EC) private key
+// and returns it as a SigningKey.
+func ParsePrivateKeyPEM(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var private interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+	return NewSigningKey(signer)
+}
+
+// Symmetric reports whether the key is a shared secret.
+func (key *SigningKey) Symmetric() bool {
+	_, ok := key.verifyKey.([]byte)
+	return ok
+}
+
+// Keyring holds the key new tokens are signed with plus any older keys that
+// are still accepted for verification. Rotating moves the active key into the
+// verification set for a grace window so tokens already issued keep working
+// until they expire.
+type Keyring struct {
+	mu      sync.RWMutex
+	active  *SigningKey
+	keys    map[string]*SigningKey
+	retired map[string]time.Time
+	now     func() time.Time
+}
+
+// NewKeyring returns a keyring that signs with active.
+func NewKeyring(active *SigningKey) *Keyring {
+	return &Keyring{
+		active:  active,
+		keys:    map[string]*SigningKey{active.ID: active},
+		retired: map[string]time.Time{},
+		now:     time.Now,
+	}
+}
+
+// AddVerificationKey accepts tokens signed by key until the given time. A
+// zero time keeps the key until it is removed from the keyring.
+func (k *Keyring) AddVerificationKey(key *SigningKey, until time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key.ID == k.active.ID {
+		return
+	}
+	k.keys[key.ID] = key
+	if until.IsZero() {
+		delete(k.retired, key.ID)
+	} else {
+		k.retired[key.ID] = until
+	}
+}
+
+// Rotate makes next the signing key. The previous active key stays valid for
+// verification for window.
+func (k *Keyring) Rotate(next *SigningKey, window time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	previous := k.active
+	k.active = next
+	k.keys[next.ID] = next
+	delete(k.retired, next.ID)
+	if previous.ID != next.ID {
+		k.retired[previous.ID] = k.now().Add(window)
+	}
+}
+
+// lookup returns the verification key for kid, dropping it if its rotation
+// window has passed.
+func (k *Keyring) lookup(kid string) (*SigningKey, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, false
+	}
+	if until, ok := k.retired[kid]; ok && !k.now().Before(until) {
+		delete(k.keys, kid)
+		delete(k.retired, kid)
+		return nil, false
+	}
+	return key, true
+}
+
+// Sign signs claims with the active key, setting the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	key := k.active
+	k.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.signKey)
+}
+
+// AccessToken describes an access token to issue.
+type AccessToken struct {
+	UserID uuid.UUID
+	Role   Role
+	Scopes []string
+	// SessionID, when set, becomes the sid claim.
+	SessionID uuid.UUID
+	// ClientID, when set, becomes the client_id claim.
+	ClientID  string
+	ExpiresIn time.Duration
+}
+
+// MakeAccessToken signs an access token with the active key.
+func (k *Keyring) MakeAccessToken(t AccessToken) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   t.UserID.String(),
+			Audience:  jwt.ClaimStrings{AccessTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.ExpiresIn)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			// The jti is what a revoked token is denylisted by.
+			ID: uuid.NewString(),
+		},
+		Role:     t.Role,
+		Scope:    strings.Join(t.Scopes, " "),
+		ClientID: t.ClientID,
+	}
+	if t.SessionID != uuid.Nil {
+		claims.SessionID = t.SessionID.String()
+	}
+	return k.Sign(claims)
+}
+
+// MakeJWT issues an access token for userID with no role or scopes.
+func (k *Keyring) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return k.MakeAccessToken(AccessToken{UserID: userID, ExpiresIn: expiresIn})
+}
+
+// MakeEmailVerificationToken issues a token proving whoever holds it
+// received mail at email. It only verifies that address, so it goes stale if
+// the user changes their email before using it.
+func (k *Keyring) MakeEmailVerificationToken(userID uuid.UUID, email string, expiresIn time.Duration) (string, error) {
+	now := time.Now()
+	return k.Sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{EmailVerificationAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Email: email,
+	})
+}
+
+// Algorithms lists the signing algorithms of every key in the keyring, which
+// is the natural allow-list for ValidatorOptions.
+func (k *Keyring) Algorithms() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	seen := map[string]bool{}
+	algs := []string{}
+	for _, key := range k.keys {
+		alg := key.Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// keyfunc picks the verification key named by the token's kid header and
+// refuses tokens whose alg doesn't match that key.
+func (k *Keyring) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is the public half of a signing key in RFC 7517 form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys other services need to verify our tokens.
+// Shared secrets are never included.
+func (k *Keyring) JWKS() JWKS {
+	k.mu.RLock()
+	ids := make([]string, 0, len(k.keys))
+	ids = append(ids, k.active.ID)
+	for id := range k.keys {
+		if id != k.active.ID {
+			ids = append(ids, id)
+		}
+	}
+	k.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		key, ok := k.lookup(id)
+		if !ok || key.Symmetric() {
+			continue
+		}
+		jwk, err := key.publicJWK()
+		if err != nil {
+			continue
+		}
+		jwk.Kid = key.ID
+		jwk.Use = "sig"
+		jwk.Alg = key.Method.Alg()
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func (key *SigningKey) publicJWK() (JWK, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	switch pub := key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   b64(pub.N.Bytes()),
+			E:   b64(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		ecdhKey, err := pub.ECDH()
+		if err != nil {
+			return JWK{}, err
+		}
+		// Uncompressed point: 0x04 || X || Y, each coordinate padded to
+		// the curve size.
+		point := ecdhKey.Bytes()
+		size := (len(point) - 1) / 2
+		return JWK{
+			Kty: "EC",
+			Crv: pub.Curve.Params().Name,
+			X:   b64(point[1 : 1+size]),
+			Y:   b64(point[1+size:]),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   b64(pub),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("no JWK form for key type %T", key.verifyKey)
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint: the SHA-256 of the
+// required members serialised in lexicographic order.
+func (jwk JWK) thumbprint() (string, error) {
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
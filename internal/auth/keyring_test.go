@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func mustSigningKey(t *testing.T, private crypto.Signer) *SigningKey {
+	t.Helper()
+	key, err := NewSigningKey(private)
+	if err != nil {
+		t.Fatalf("failed to create signing key: %v", err)
+	}
+	return key
+}
+
+func testValidatorOptions(keyring *Keyring) ValidatorOptions {
+	return ValidatorOptions{
+		Algorithms:     keyring.Algorithms(),
+		Issuer:         Issuer,
+		Audiences:      []string{AccessTokenAudience},
+		RequiredClaims: []string{"sub", "exp", "iat"},
+	}
+}
+
+func testSigningKeys(t *testing.T) map[string]*SigningKey {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+	return map[string]*SigningKey{
+		"RS256": mustSigningKey(t, rsaKey),
+		"ES256": mustSigningKey(t, ecKey),
+		"EdDSA": mustSigningKey(t, edKey),
+	}
+}
+
+// This test ensures every supported algorithm can sign and verify its own tokens
+func TestKeyringSignAndValidate(t *testing.T) {
+	for alg, key := range testSigningKeys(t) {
+		t.Run(alg, func(t *testing.T) {
+			if key.Method.Alg() != alg {
+				t.Fatalf("got alg %s, want %s", key.Method.Alg(), alg)
+			}
+
+			keyring := NewKeyring(key)
+			userID := uuid.New()
+			tokenString, err := keyring.MakeJWT(userID, time.Hour)
+			if err != nil {
+				t.Fatalf("failed to make JWT: %v", err)
+			}
+
+			token, _, err := jwt.NewParser().ParseUnverified(tokenString, &jwt.RegisteredClaims{})
+			if err != nil {
+				t.Fatalf("failed to parse JWT: %v", err)
+			}
+			if token.Header["kid"] != key.ID {
+				t.Errorf("kid header = %v, want %s", token.Header["kid"], key.ID)
+			}
+
+			claims, err := keyring.ValidateJWT(tokenString, testValidatorOptions(keyring))
+			if err != nil {
+				t.Fatalf("failed to validate JWT: %v", err)
+			}
+			if claims.UserID != userID {
+				t.Errorf("validated user ID %s does not match original user ID %s", claims.UserID, userID)
+			}
+		})
+	}
+}
+
+// This test ensures tokens from a rotated-out key are accepted only during the rotation window
+func TestKeyringRotation(t *testing.T) {
+	keys := testSigningKeys(t)
+	keyring := NewKeyring(keys["RS256"])
+	now := time.Now()
+	keyring.now = func() time.Time { return now }
+
+	oldToken, err := keyring.MakeJWT(uuid.New(), 2*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make JWT: %v", err)
+	}
+
+	keyring.Rotate(keys["ES256"], time.Hour)
+
+	newToken, err := keyring.MakeJWT(uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make JWT: %v", err)
+	}
+	if _, err := keyring.ValidateJWT(newToken, testValidatorOptions(keyring)); err != nil {
+		t.Errorf("token from new key rejected: %v", err)
+	}
+	if _, err := keyring.ValidateJWT(oldToken, testValidatorOptions(keyring)); err != nil {
+		t.Errorf("token from previous key rejected during rotation window: %v", err)
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	if _, err := keyring.ValidateJWT(oldToken, testValidatorOptions(keyring)); err == nil {
+		t.Errorf("token from previous key accepted after rotation window")
+	}
+	if len(keyring.JWKS().Keys) != 1 {
+		t.Errorf("JWKS still lists retired key")
+	}
+}
+
+// This test ensures tokens from keys outside the keyring are rejected
+func TestKeyringUnknownKey(t *testing.T) {
+	keys := testSigningKeys(t)
+	signer := NewKeyring(keys["EdDSA"])
+	verifier := NewKeyring(keys["RS256"])
+
+	tokenString, err := signer.MakeJWT(uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make JWT: %v", err)
+	}
+	if _, err := verifier.ValidateJWT(tokenString, testValidatorOptions(verifier)); err == nil {
+		t.Fatalf("validated JWT signed by an unknown key, expected an error")
+	}
+}
+
+// This test ensures the JWKS publishes asymmetric keys only
+func TestKeyringJWKS(t *testing.T) {
+	keys := testSigningKeys(t)
+	keyring := NewKeyring(keys["ES256"])
+	keyring.AddVerificationKey(keys["RS256"], time.Time{})
+	keyring.AddVerificationKey(NewHMACKey("", []byte("my-test-secret")), time.Time{})
+
+	set := keyring.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(set.Keys))
+	}
+	if set.Keys[0].Kid != keys["ES256"].ID || set.Keys[0].Kty != "EC" || set.Keys[0].Crv != "P-256" {
+		t.Errorf("active key not listed first: %+v", set.Keys[0])
+	}
+	for _, jwk := range set.Keys {
+		if jwk.Kty == "oct" || jwk.Kid == "" {
+			t.Errorf("shared secret published in JWKS: %+v", jwk)
+		}
+	}
+}
+
+// This test ensures PEM-encoded keys round-trip to the same key ID
+func TestParsePrivateKeyPEM(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	key, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		t.Fatalf("failed to parse PEM: %v", err)
+	}
+	if want := mustSigningKey(t, ecKey).ID; key.ID != want {
+		t.Errorf("kid = %s, want %s", key.ID, want)
+	}
+
+	if _, err := ParsePrivateKeyPEM([]byte("not a key")); err == nil {
+		t.Errorf("parsed garbage as a key, expected an error")
+	}
+}
+
+// This test ensures verification tokens carry the email and only validate for their own audience
+func TestEmailVerificationToken(t *testing.T) {
+	keyring := NewKeyring(NewHMACKey("test", []byte("secret")))
+	userID := uuid.New()
+
+	token, err := keyring.MakeEmailVerificationToken(userID, "user@example.com", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeEmailVerificationToken() error = %v", err)
+	}
+
+	opts := ValidatorOptions{Algorithms: keyring.Algorithms(), Issuer: Issuer}
+
+	opts.Audiences = []string{AccessTokenAudience}
+	if _, err := keyring.ValidateJWT(token, opts); err == nil {
+		t.Errorf("Verification token accepted as an access token")
+	}
+
+	opts.Audiences = []string{EmailVerificationAudience}
+	claims, err := keyring.ValidateJWT(token, opts)
+	if err != nil {
+		t.Fatalf("ValidateJWT() error = %v", err)
+	}
+	if claims.UserID != userID || claims.Email != "user@example.com" {
+		t.Errorf("ValidateJWT() = %v %q, want %v %q", claims.UserID, claims.Email, userID, "user@example.com")
+	}
+}
+
+// This test ensures access tokens carry the session ID only when one is given
+func TestAccessTokenSessionID(t *testing.T) {
+	keyring := NewKeyring(NewHMACKey("test", []byte("secret")))
+	opts := ValidatorOptions{Algorithms: keyring.Algorithms(), Audiences: []string{AccessTokenAudience}}
+	sessionID := uuid.New()
+
+	tests := []struct {
+		name      string
+		sessionID uuid.UUID
+		want      string
+	}{
+		{name: "With session", sessionID: sessionID, want: sessionID.String()},
+		{name: "Without session", sessionID: uuid.Nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := keyring.MakeAccessToken(AccessToken{
+				UserID:    uuid.New(),
+				SessionID: tt.sessionID,
+				ExpiresIn: time.Hour,
+			})
+			if err != nil {
+				t.Fatalf("MakeAccessToken() error = %v", err)
+			}
+			claims, err := keyring.ValidateJWT(token, opts)
+			if err != nil {
+				t.Fatalf("ValidateJWT() error = %v", err)
+			}
+			if claims.SessionID != tt.want {
+				t.Errorf("SessionID = %q, want %q", claims.SessionID, tt.want)
+			}
+		})
+	}
+}
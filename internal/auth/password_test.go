@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// This test ensures argon2id hashes verify and are stored as PHC strings
+func TestPasswordHasherArgon2id(t *testing.T) {
+	hasher := PasswordHasher{Params: DefaultArgon2Params}
+	hash, err := hasher.Hash("correctPassword123!")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("unexpected hash format: %s", hash)
+	}
+	if err := hasher.Check("correctPassword123!", hash); err != nil {
+		t.Errorf("correct password rejected: %v", err)
+	}
+	if err := hasher.Check("wrongPassword", hash); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("wrong password: got error %v, want ErrPasswordMismatch", err)
+	}
+	if hasher.NeedsRehash(hash) {
+		t.Errorf("fresh hash reported as needing a rehash")
+	}
+}
+
+// This test ensures passwords longer than bcrypt's 72-byte limit are not truncated
+func TestPasswordHasherLongPasswords(t *testing.T) {
+	hasher := PasswordHasher{Params: DefaultArgon2Params}
+	long := strings.Repeat("a", 72)
+	hash, err := hasher.Hash(long + "suffix-one")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if err := hasher.Check(long+"suffix-two", hash); err == nil {
+		t.Errorf("password differing after byte 72 was accepted")
+	}
+}
+
+// This test ensures legacy bcrypt hashes still verify but are flagged for rehashing
+func TestPasswordHasherBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correctPassword123!"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to make bcrypt hash: %v", err)
+	}
+	hasher := PasswordHasher{Params: DefaultArgon2Params}
+
+	if err := hasher.Check("correctPassword123!", string(legacy)); err != nil {
+		t.Errorf("correct password rejected: %v", err)
+	}
+	if err := hasher.Check("wrongPassword", string(legacy)); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("wrong password: got error %v, want ErrPasswordMismatch", err)
+	}
+	if !hasher.NeedsRehash(string(legacy)) {
+		t.Errorf("bcrypt hash not flagged for rehashing")
+	}
+}
+
+// This test ensures hashes made with other parameters are flagged for rehashing
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	weak := DefaultArgon2Params
+	weak.Memory = 8 * 1024
+	hash, err := PasswordHasher{Params: weak}.Hash("correctPassword123!")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	hasher := PasswordHasher{Params: DefaultArgon2Params}
+	if err := hasher.Check("correctPassword123!", hash); err != nil {
+		t.Errorf("hash with older parameters rejected: %v", err)
+	}
+	if !hasher.NeedsRehash(hash) {
+		t.Errorf("hash with older parameters not flagged for rehashing")
+	}
+	if !hasher.NeedsRehash("unset") {
+		t.Errorf("unknown hash format not flagged for rehashing")
+	}
+}
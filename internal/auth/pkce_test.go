@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// rfc7636Verifier and rfc7636Challenge are the example from RFC 7636
+// appendix B.
+const (
+	rfc7636Verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfc7636Challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+// This test ensures the S256 challenge matches the RFC 7636 example
+func TestPKCEChallenge(t *testing.T) {
+	if got := PKCEChallenge(rfc7636Verifier); got != rfc7636Challenge {
+		t.Errorf("PKCEChallenge() = %q, want %q", got, rfc7636Challenge)
+	}
+	if !ValidPKCEChallenge(rfc7636Challenge) {
+		t.Errorf("ValidPKCEChallenge(%q) = false, want true", rfc7636Challenge)
+	}
+}
+
+// This test ensures only the verifier a challenge was made from is accepted
+func TestVerifyPKCE(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+		wantErr  bool
+	}{
+		{name: "Matching verifier", verifier: rfc7636Verifier, wantErr: false},
+		{name: "Other verifier", verifier: strings.Repeat("a", 43), wantErr: true},
+		{name: "Too short", verifier: "short", wantErr: true},
+		{name: "Too long", verifier: strings.Repeat("a", 129), wantErr: true},
+		{name: "Invalid characters", verifier: strings.Repeat("a", 42) + "+", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyPKCE(tt.verifier, rfc7636Challenge)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyPKCE() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := VerifyPKCE(strings.Repeat("a", 43), rfc7636Challenge); !errors.Is(err, ErrPKCEMismatch) {
+		t.Errorf("VerifyPKCE() error = %v, want %v", err, ErrPKCEMismatch)
+	}
+}
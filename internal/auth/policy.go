@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Password policy rule names reported in PolicyViolation.Rule.
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleNotEmail  = "not_email"
+	RuleBreached  = "breached"
+)
+
+// PasswordPolicy describes which passwords users may choose.
+type PasswordPolicy struct {
+	// MinLength and MaxLength count Unicode code points. Zero disables
+	// the check.
+	MinLength int
+	MaxLength int
+	// DisallowEmail rejects the user's email address, or its local part,
+	// as a password.
+	DisallowEmail bool
+	// Breached, when set, rejects passwords found in a breach corpus.
+	Breached *BreachedPasswords
+}
+
+// PolicyViolation is a single rule a password failed.
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a password failed.
+type PasswordPolicyError struct {
+	Violations []PolicyViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return "password violates policy: " + strings.Join(rules, ", ")
+}
+
+// Validate checks password for the account with the given email. It returns
+// a *PasswordPolicyError naming every failed rule, or another error if the
+// breach corpus couldn't be read.
+func (p PasswordPolicy) Validate(password, email string) error {
+	violations := []PolicyViolation{}
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, PolicyViolation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("Password must be at least %d characters", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, PolicyViolation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("Password must be at most %d characters", p.MaxLength),
+		})
+	}
+
+	if p.DisallowEmail && email != "" {
+		local, _, _ := strings.Cut(email, "@")
+		if strings.EqualFold(password, email) || strings.EqualFold(password, local) {
+			violations = append(violations, PolicyViolation{
+				Rule:    RuleNotEmail,
+				Message: "Password must not be your email address",
+			})
+		}
+	}
+
+	if p.Breached != nil && password != "" {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, PolicyViolation{
+				Rule:    RuleBreached,
+				Message: "Password has appeared in a data breach, choose another",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// BreachedPasswords looks passwords up in an on-disk breach corpus laid out
+// like the Pwned Passwords range API: one file per 5-character SHA-1 prefix
+// (e.g. "21BD1" or "21BD1.txt"), each line holding the remaining 35 hex
+// characters of a hash and a count, "SUFFIX:COUNT". Only the prefix file is
+// read, and nothing leaves the machine.
+type BreachedPasswords struct {
+	Dir string
+}
+
+// Contains reports whether password appears in the corpus.
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	f, err := b.openRange(prefix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		candidate, _, _ := strings.Cut(line, ":")
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func (b *BreachedPasswords) openRange(prefix string) (*os.File, error) {
+	f, err := os.Open(filepath.Join(b.Dir, prefix))
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.Open(filepath.Join(b.Dir, prefix+".txt"))
+	}
+	return f, err
+}
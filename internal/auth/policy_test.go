@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// This test ensures the password policy reports every rule a password breaks
+func TestPasswordPolicyValidate(t *testing.T) {
+	dir := t.TempDir()
+	// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	corpus := "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n"
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(corpus), 0o600); err != nil {
+		t.Fatalf("failed to write corpus: %v", err)
+	}
+
+	policy := PasswordPolicy{
+		MinLength:     8,
+		MaxLength:     64,
+		DisallowEmail: true,
+		Breached:      &BreachedPasswords{Dir: dir},
+	}
+
+	tests := []struct {
+		name      string
+		password  string
+		email     string
+		wantRules []string
+	}{
+		{
+			name:     "Acceptable password",
+			password: "correct horse battery staple",
+			email:    "walt@example.com",
+		},
+		{
+			name:      "Empty password",
+			password:  "",
+			email:     "walt@example.com",
+			wantRules: []string{RuleMinLength},
+		},
+		{
+			name:      "Too short",
+			password:  "abc123",
+			email:     "walt@example.com",
+			wantRules: []string{RuleMinLength},
+		},
+		{
+			name:     "Length counts characters, not bytes",
+			password: "пароль!!",
+			email:    "walt@example.com",
+		},
+		{
+			name:      "Too long",
+			password:  string(make([]byte, 65)),
+			email:     "walt@example.com",
+			wantRules: []string{RuleMaxLength},
+		},
+		{
+			name:      "Email as password",
+			password:  "Walt@Example.com",
+			email:     "walt@example.com",
+			wantRules: []string{RuleNotEmail},
+		},
+		{
+			name:      "Email local part as password",
+			password:  "walterwhite",
+			email:     "WalterWhite@example.com",
+			wantRules: []string{RuleNotEmail},
+		},
+		{
+			name:      "Breached password",
+			password:  "password",
+			email:     "walt@example.com",
+			wantRules: []string{RuleBreached},
+		},
+		{
+			name:      "Several rules at once",
+			password:  "walt",
+			email:     "walt@example.com",
+			wantRules: []string{RuleMinLength, RuleNotEmail},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, tt.email)
+			if len(tt.wantRules) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Validate() error = %v, want *PasswordPolicyError", err)
+			}
+			if len(policyErr.Violations) != len(tt.wantRules) {
+				t.Fatalf("got violations %+v, want rules %v", policyErr.Violations, tt.wantRules)
+			}
+			for i, rule := range tt.wantRules {
+				if policyErr.Violations[i].Rule != rule {
+					t.Errorf("violation %d = %s, want %s", i, policyErr.Violations[i].Rule, rule)
+				}
+			}
+		})
+	}
+}
+
+// This test ensures a missing range file means the password isn't in the corpus
+func TestBreachedPasswordsMissingRange(t *testing.T) {
+	breached := &BreachedPasswords{Dir: t.TempDir()}
+	found, err := breached.Contains("password")
+	if err != nil {
+		t.Fatalf("Contains() error = %v", err)
+	}
+	if found {
+		t.Errorf("Contains() = true for an empty corpus")
+	}
+}
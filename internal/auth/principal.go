@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID uuid.UUID
+	Role   Role
+	// Scopes limits what the caller may do on the user's behalf.
+	Scopes []string
+	// TokenID is the jti of the access token the caller presented.
+	TokenID string
+	// SessionID is the login session the token belongs to, or uuid.Nil for
+	// tokens issued without one.
+	SessionID uuid.UUID
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether the principal holds role and was granted the scope
+// that unlocks it, so a narrowly scoped token from an admin stays narrow.
+func (p Principal) HasRole(role Role) bool {
+	if !p.Role.AtLeast(role) {
+		return false
+	}
+	return role.Scope() == "" || p.HasScope(role.Scope())
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored by WithPrincipal, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
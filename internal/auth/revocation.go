@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTokenRevoked is returned by ValidateJWTContext for a token that is
+// otherwise valid but has been revoked.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Revocations decides whether a validated token has since been revoked,
+// for example because its jti was denylisted or its user changed their
+// password after it was issued.
+type Revocations interface {
+	Revoked(ctx context.Context, claims *Claims) (bool, error)
+}
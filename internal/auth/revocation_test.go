@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type denylist map[string]bool
+
+func (d denylist) Revoked(ctx context.Context, claims *Claims) (bool, error) {
+	return d[claims.ID], nil
+}
+
+// This test ensures access tokens get a unique jti and revoked ones are rejected
+func TestValidateJWTRevocation(t *testing.T) {
+	keyring := NewKeyring(NewHMACKey("test", []byte("secret")))
+	makeToken := func() (string, string) {
+		token, err := keyring.MakeAccessToken(AccessToken{UserID: uuid.New(), ExpiresIn: time.Hour})
+		if err != nil {
+			t.Fatalf("MakeAccessToken() error = %v", err)
+		}
+		claims, err := keyring.ValidateJWT(token, ValidatorOptions{Algorithms: keyring.Algorithms()})
+		if err != nil {
+			t.Fatalf("ValidateJWT() error = %v", err)
+		}
+		return token, claims.ID
+	}
+
+	revokedToken, revokedID := makeToken()
+	liveToken, liveID := makeToken()
+	if revokedID == "" || revokedID == liveID {
+		t.Fatalf("jti = %q and %q, want distinct non-empty IDs", revokedID, liveID)
+	}
+
+	opts := ValidatorOptions{
+		Algorithms:     keyring.Algorithms(),
+		RequiredClaims: []string{"jti"},
+		Revocations:    denylist{revokedID: true},
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "Live token", token: liveToken},
+		{name: "Revoked token", token: revokedToken, wantErr: ErrTokenRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := keyring.ValidateJWTContext(context.Background(), tt.token, opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateJWTContext() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import "fmt"
+
+// Role is a user's level of privilege. Each role includes the privileges of
+// the roles below it.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Scopes carried in the scope claim of access tokens.
+const (
+	ScopeChirpsRead  = "chirps:read"
+	ScopeChirpsWrite = "chirps:write"
+	ScopeAccount     = "account"
+	ScopeModerate    = "moderate"
+	ScopeAdmin       = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ParseRole validates s as a role name.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+// AtLeast reports whether r has every privilege of other.
+func (r Role) AtLeast(other Role) bool {
+	rank, ok := roleRank[r]
+	return ok && rank >= roleRank[other]
+}
+
+// Scope is the scope a token needs before r's extra privileges apply.
+// Plain users need no special scope.
+func (r Role) Scope() string {
+	switch r {
+	case RoleAdmin:
+		return ScopeAdmin
+	case RoleModerator:
+		return ScopeModerate
+	default:
+		return ""
+	}
+}
+
+// Scopes lists every scope a user with role r may be granted.
+func (r Role) Scopes() []string {
+	scopes := []string{ScopeChirpsRead, ScopeChirpsWrite, ScopeAccount}
+	if r.AtLeast(RoleModerator) {
+		scopes = append(scopes, ScopeModerate)
+	}
+	if r.AtLeast(RoleAdmin) {
+		scopes = append(scopes, ScopeAdmin)
+	}
+	return scopes
+}
@@ -0,0 +1,58 @@
+package auth
+
+import "testing"
+
+// This test ensures roles unlock only with both the role and its scope
+func TestPrincipalHasRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		role      Role
+		want      bool
+	}{
+		{
+			name:      "User acting as user",
+			principal: Principal{Role: RoleUser, Scopes: RoleUser.Scopes()},
+			role:      RoleUser,
+			want:      true,
+		},
+		{
+			name:      "User acting as moderator",
+			principal: Principal{Role: RoleUser, Scopes: RoleUser.Scopes()},
+			role:      RoleModerator,
+			want:      false,
+		},
+		{
+			name:      "Admin acting as moderator",
+			principal: Principal{Role: RoleAdmin, Scopes: RoleAdmin.Scopes()},
+			role:      RoleModerator,
+			want:      true,
+		},
+		{
+			name:      "Admin with a token scoped to chirps only",
+			principal: Principal{Role: RoleAdmin, Scopes: []string{ScopeChirpsWrite}},
+			role:      RoleAdmin,
+			want:      false,
+		},
+		{
+			name:      "User with a forged admin scope",
+			principal: Principal{Role: RoleUser, Scopes: []string{ScopeAdmin}},
+			role:      RoleAdmin,
+			want:      false,
+		},
+		{
+			name:      "Unknown role",
+			principal: Principal{Role: "root", Scopes: []string{ScopeAdmin}},
+			role:      RoleUser,
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.HasRole(tt.role); got != tt.want {
+				t.Errorf("HasRole(%s) = %v, want %v", tt.role, got, tt.want)
+			}
+		})
+	}
+}
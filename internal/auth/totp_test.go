@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 appendix B,
+// "12345678901234567890", in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// This test ensures codes match the RFC 6238 test vectors, truncated to six digits
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		name string
+		time int64
+		want string
+	}{
+		{name: "T=59", time: 59, want: "287082"},
+		{name: "T=1111111109", time: 1111111109, want: "081804"},
+		{name: "T=1111111111", time: 1111111111, want: "050471"},
+		{name: "T=1234567890", time: 1234567890, want: "005924"},
+		{name: "T=2000000000", time: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TOTPCode(rfc6238Secret, time.Unix(tt.time, 0))
+			if err != nil {
+				t.Fatalf("TOTPCode() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TOTPCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// This test ensures codes are accepted one step either side of now and no further
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	codeAt := func(offset time.Duration) string {
+		code, _ := TOTPCode(rfc6238Secret, now.Add(offset))
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantErr  bool
+	}{
+		{name: "Current step", code: codeAt(0), wantStep: 1234567890 / 30},
+		{name: "Previous step", code: codeAt(-30 * time.Second), wantStep: 1234567890/30 - 1},
+		{name: "Next step", code: codeAt(30 * time.Second), wantStep: 1234567890/30 + 1},
+		{name: "Two steps ago", code: codeAt(-60 * time.Second), wantErr: true},
+		{name: "Wrong code", code: "000000", wantErr: true},
+		{name: "Wrong length", code: "12345", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := ValidateTOTP(rfc6238Secret, tt.code, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateTOTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && step != tt.wantStep {
+				t.Errorf("ValidateTOTP() step = %v, want %v", step, tt.wantStep)
+			}
+		})
+	}
+}
+
+// This test ensures a fresh secret round-trips through code generation and validation
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error = %v", err)
+	}
+	now := time.Now()
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("TOTPCode() error = %v", err)
+	}
+	if _, err := ValidateTOTP(secret, code, now); err != nil {
+		t.Errorf("ValidateTOTP() error = %v", err)
+	}
+}
+
+// This test ensures recovery codes hash the same however the user types them
+func TestHashRecoveryCode(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("GenerateRecoveryCodes() returned %d codes, want 10", len(codes))
+	}
+
+	code := codes[0]
+	want := HashRecoveryCode(code)
+	for _, typed := range []string{
+		strings.ToUpper(code),
+		strings.ReplaceAll(code, "-", ""),
+		strings.ReplaceAll(code, "-", " "),
+	} {
+		if got := HashRecoveryCode(typed); got != want {
+			t.Errorf("HashRecoveryCode(%q) = %v, want %v", typed, got, want)
+		}
+	}
+	if HashRecoveryCode(codes[1]) == want {
+		t.Errorf("Different recovery codes hashed the same")
+	}
+}
+
+// This test ensures challenge tokens can't be used as access tokens
+func TestChallengeTokenAudience(t *testing.T) {
+	keyring := NewKeyring(NewHMACKey("test", []byte("secret")))
+	userID := uuid.New()
+
+	challenge, err := keyring.MakeChallengeToken(userID, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeChallengeToken() error = %v", err)
+	}
+
+	opts := ValidatorOptions{Algorithms: keyring.Algorithms(), Issuer: Issuer}
+
+	opts.Audiences = []string{AccessTokenAudience}
+	if _, err := keyring.ValidateJWT(challenge, opts); err == nil {
+		t.Errorf("Challenge token accepted as an access token")
+	}
+
+	opts.Audiences = []string{TwoFactorAudience}
+	claims, err := keyring.ValidateJWT(challenge, opts)
+	if err != nil {
+		t.Fatalf("ValidateJWT() error = %v", err)
+	}
+	if claims.UserID != userID {
+		t.Errorf("ValidateJWT() UserID = %v, want %v", claims.UserID, userID)
+	}
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Claims are the claims carried by tokens this server issues.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role Role `json:"role,omitempty"`
+	// Scope is a space-separated list of scopes, as in RFC 8693.
+	Scope string `json:"scope,omitempty"`
+	// SessionID names the login session (refresh token family) an access
+	// token was issued to, so ending the session can reject the token.
+	SessionID string `json:"sid,omitempty"`
+	// Email is the address an email verification token was sent to.
+	Email string `json:"email,omitempty"`
+	// ClientID names the OAuth client an access token was issued to, as in
+	// RFC 9068. It is empty for first-party tokens.
+	ClientID string `json:"client_id,omitempty"`
+
+	// UserID is the subject parsed as a user ID. ValidateJWT fills it in.
+	UserID uuid.UUID `json:"-"`
+}
+
+// Scopes splits the scope claim.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// ValidatorOptions controls which tokens ValidateJWT accepts.
+type ValidatorOptions struct {
+	// Algorithms is the allow-list of alg header values. The token must
+	// also use the algorithm of the key its kid names.
+	Algorithms []string
+	// Issuer, when set, must equal the iss claim.
+	Issuer string
+	// Audiences, when set, must intersect the aud claim.
+	Audiences []string
+	// Leeway is the clock skew tolerated on exp, nbf and iat.
+	Leeway time.Duration
+	// RequiredClaims must be present: any of "sub", "exp", "iat", "nbf",
+	// "iss", "aud", "jti" and "sid".
+	RequiredClaims []string
+	// Revocations, when set, is consulted once a token has passed every
+	// other check.
+	Revocations Revocations
+}
+
+// ValidateJWT verifies tokenString against the keyring and opts. The subject
+// must be a user ID.
+func (k *Keyring) ValidateJWT(tokenString string, opts ValidatorOptions) (*Claims, error) {
+	return k.ValidateJWTContext(context.Background(), tokenString, opts)
+}
+
+// ValidateJWTContext is ValidateJWT with a context for the revocation check.
+func (k *Keyring) ValidateJWTContext(ctx context.Context, tokenString string, opts ValidatorOptions) (*Claims, error) {
+	if len(opts.Algorithms) == 0 {
+		return nil, errors.New("no signing algorithms allowed")
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(opts.Algorithms),
+		jwt.WithLeeway(opts.Leeway),
+		jwt.WithIssuedAt(),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if len(opts.Audiences) > 0 {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audiences...))
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, k.keyfunc, parserOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if err := claims.require(opts.RequiredClaims); err != nil {
+		return nil, err
+	}
+
+	claims.UserID, err = uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject: %w", err)
+	}
+
+	if opts.Revocations != nil {
+		revoked, err := opts.Revocations.Revoked(ctx, claims)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't check revocation: %w", err)
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+	return claims, nil
+}
+
+func (c *Claims) require(names []string) error {
+	for _, name := range names {
+		var present bool
+		switch name {
+		case "sub":
+			present = c.Subject != ""
+		case "exp":
+			present = c.ExpiresAt != nil
+		case "iat":
+			present = c.IssuedAt != nil
+		case "nbf":
+			present = c.NotBefore != nil
+		case "iss":
+			present = c.Issuer != ""
+		case "aud":
+			present = len(c.Audience) > 0
+		case "jti":
+			present = c.ID != ""
+		case "sid":
+			present = c.SessionID != ""
+		default:
+			return fmt.Errorf("unsupported required claim %q", name)
+		}
+		if !present {
+			return fmt.Errorf("token is missing required claim %q", name)
+		}
+	}
+	return nil
+}
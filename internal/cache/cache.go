@@ -0,0 +1,79 @@
+// Package cache provides a small in-memory map whose entries expire.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often Set clears out expired entries, so keys that
+// are never read again don't pile up.
+const sweepInterval = time.Minute
+
+// TTL is a map whose entries expire after a per-entry time to live. It is
+// safe for concurrent use.
+type TTL[K comparable, V any] struct {
+	mu        sync.Mutex
+	entries   map[K]entry[V]
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// New returns an empty cache.
+func New[K comparable, V any]() *TTL[K, V] {
+	return &TTL[K, V]{
+		entries: map[K]entry[V]{},
+		now:     time.Now,
+	}
+}
+
+// Get returns the value for key if it is present and hasn't expired.
+func (c *TTL[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if !c.now().Before(e.expiresAt) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores value for key until ttl has passed.
+func (c *TTL[K, V]) Set(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.entries[key] = entry[V]{value: value, expiresAt: now.Add(ttl)}
+	if now.Sub(c.lastSweep) >= sweepInterval {
+		c.sweep(now)
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet swept.
+func (c *TTL[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *TTL[K, V]) sweep(now time.Time) {
+	for key, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	c.lastSweep = now
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// This test ensures entries are returned until their TTL passes and not after
+func TestTTLGet(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := New[string, int]()
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1, time.Minute)
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		wantOK  bool
+	}{
+		{name: "Fresh", elapsed: 0, wantOK: true},
+		{name: "Just before expiry", elapsed: time.Minute - time.Second, wantOK: true},
+		{name: "At expiry", elapsed: time.Minute, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = time.Unix(1000, 0).Add(tt.elapsed)
+			got, ok := c.Get("a")
+			if ok != tt.wantOK {
+				t.Fatalf("Get() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got != 1 {
+				t.Errorf("Get() = %v, want 1", got)
+			}
+		})
+	}
+}
+
+// This test ensures expired entries are swept even if they are never read again
+func TestTTLSweep(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := New[string, bool]()
+	c.now = func() time.Time { return now }
+
+	c.Set("old", true, time.Second)
+	now = now.Add(2 * sweepInterval)
+	c.Set("new", true, time.Hour)
+
+	if got := c.Len(); got != 1 {
+		t.Errorf("Len() = %v, want 1", got)
+	}
+}
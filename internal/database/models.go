@@ -11,27 +11,141 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	KeyID      string
+	KeyHash    string
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
 type Chirp struct {
-	ID        uuid.UUID
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.UUID
+	EditedAt    sql.NullTime
+	InReplyToID uuid.NullUUID
+	RootID      uuid.NullUUID
+	ReplyCount  int32
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	WrittenAt  time.Time
+	ReplacedAt time.Time
+}
+
+type LoginAttempt struct {
+	Key          string
+	FailedCount  int32
+	LastFailedAt time.Time
+	BlockedUntil sql.NullTime
+}
+
+type MagicLinkToken struct {
+	TokenHash string
+	UserID    uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type ModerationFlag struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Rule       string
+	Excerpt    string
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+	ResolvedBy uuid.NullUUID
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scope         string
+	CodeChallenge string
+	FamilyID      uuid.UUID
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+}
+
+type PasswordResetToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
 }
 
-type RefreshToken struct {
-	Token     string
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	TokenHash  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	RotatedAt  sql.NullTime
+	UserAgent  string
+	Ip         string
+	LastUsedAt time.Time
+	ClientID   uuid.NullUUID
+	Scope      sql.NullString
+}
+
+type RevokedAccessToken struct {
+	Jti       string
 	UserID    uuid.UUID
+	RevokedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Email               string
+	HashedPassword      string
+	Role                string
+	TotpSecret          sql.NullString
+	TotpEnabledAt       sql.NullTime
+	TotpLastUsedStep    sql.NullInt64
+	EmailVerifiedAt     sql.NullTime
+	DeletionRequestedAt sql.NullTime
+	DeleteAfter         sql.NullTime
+	Handle              string
+	DisplayName         string
+	Bio                 string
+	AvatarUrl           string
 }
@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// This test ensures the writer produces a well-formed message with CRLF line endings
+func TestWriterSend(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewWriter(&buf, "Chirpy <no-reply@example.com>")
+
+	err := m.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Verify your email",
+		Body:    "Hello\nWorld",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		"From: Chirpy <no-reply@example.com>\r\n",
+		"To: user@example.com\r\n",
+		"Subject: Verify your email\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nHello\r\nWorld\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Send() output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+// This test ensures header values can't smuggle in extra headers
+func TestHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{
+			name: "Line break in recipient",
+			msg:  Message{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi"},
+		},
+		{
+			name: "Line break in subject",
+			msg:  Message{To: "user@example.com", Subject: "Hi\nBcc: victim@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewWriter(&buf, "no-reply@example.com").Send(context.Background(), tt.msg)
+			if !errors.Is(err, ErrHeaderInjection) {
+				t.Errorf("Send() error = %v, want %v", err, ErrHeaderInjection)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("Send() wrote %q despite the error", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package moderation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Config is the rules file format:
+//
+//	{
+//	  "rules": [
+//	    {"name": "profanity", "type": "words", "action": "mask", "words": ["kerfuffle"]},
+//	    {"name": "spam", "type": "links", "action": "reject", "domains": ["spam.example"]},
+//	    {"name": "phone", "type": "regex", "action": "flag", "pattern": "\\d{3}-\\d{4}"}
+//	  ]
+//	}
+type Config struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+// RuleConfig describes one rule. Which of Words, Pattern and Domains is
+// used depends on Type.
+type RuleConfig struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Action  Action   `json:"action"`
+	Words   []string `json:"words,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+	Domains []string `json:"domains,omitempty"`
+}
+
+// Rule types.
+const (
+	TypeWords = "words"
+	TypeRegex = "regex"
+	TypeLinks = "links"
+)
+
+// filter builds the Filter rc describes.
+func (rc RuleConfig) filter() (Filter, error) {
+	if rc.Name == "" {
+		return nil, errors.New("rule has no name")
+	}
+	if !rc.Action.valid() {
+		return nil, fmt.Errorf("rule %q: unknown action %q", rc.Name, rc.Action)
+	}
+
+	var f Filter
+	var err error
+	switch rc.Type {
+	case TypeWords:
+		f, err = NewWordList(rc.Name, rc.Action, rc.Words)
+	case TypeRegex:
+		f, err = NewRegex(rc.Name, rc.Action, rc.Pattern)
+	case TypeLinks:
+		f, err = NewLinkBlocklist(rc.Name, rc.Action, rc.Domains)
+	default:
+		return nil, fmt.Errorf("rule %q: unknown type %q", rc.Name, rc.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %w", rc.Name, err)
+	}
+	return f, nil
+}
+
+// ParseConfig builds a pipeline from a JSON rules file. Rules run in the
+// order they are listed.
+func ParseConfig(data []byte) (*Pipeline, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	config := Config{}
+	if err := decoder.Decode(&config); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	filters := []Filter{}
+	for _, rc := range config.Rules {
+		if seen[rc.Name] {
+			return nil, fmt.Errorf("rule %q is defined twice", rc.Name)
+		}
+		seen[rc.Name] = true
+		f, err := rc.filter()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return NewPipeline(filters...), nil
+}
+
+// LoadFile builds a pipeline from the rules file at path.
+func LoadFile(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return pipeline, nil
+}
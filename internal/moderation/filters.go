@@ -0,0 +1,187 @@
+package moderation
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// WordList matches whole words from a list. Words are compared after
+// Unicode normalisation, so case, accents and compatibility forms such as
+// fullwidth letters don't hide a word, and punctuation around a word
+// doesn't either.
+type WordList struct {
+	name   string
+	action Action
+	words  map[string]bool
+}
+
+// NewWordList returns a filter for words. Each entry must be a single word.
+func NewWordList(name string, action Action, words []string) (*WordList, error) {
+	w := &WordList{name: name, action: action, words: map[string]bool{}}
+	for _, word := range words {
+		spans := wordSpans(word)
+		if len(spans) != 1 || spans[0][0] != 0 || spans[0][1] != len(word) {
+			return nil, fmt.Errorf("%q isn't a single word; use a regex rule for phrases", word)
+		}
+		w.words[normalizeWord(word)] = true
+	}
+	return w, nil
+}
+
+// Check implements Filter.
+func (w *WordList) Check(text string) []Match {
+	matches := []Match{}
+	for _, span := range wordSpans(text) {
+		if w.words[normalizeWord(text[span[0]:span[1]])] {
+			matches = append(matches, Match{Rule: w.name, Action: w.action, Start: span[0], End: span[1]})
+		}
+	}
+	return matches
+}
+
+// isWordRune reports whether r can be part of a word. Combining marks are
+// included so accented letters written in decomposed form stay whole.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// wordSpans returns the byte offsets of every maximal run of word runes.
+func wordSpans(text string) [][2]int {
+	spans := [][2]int{}
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// normalizeWord folds a word to the form word lists compare: compatibility
+// decomposed, without combining marks, and case-folded.
+func normalizeWord(word string) string {
+	decomposed := norm.NFKD.String(word)
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, decomposed)
+	return cases.Fold().String(stripped)
+}
+
+// Regex matches a regular expression anywhere in the text.
+type Regex struct {
+	name    string
+	action  Action
+	pattern *regexp.Regexp
+}
+
+// NewRegex returns a filter for pattern, in RE2 syntax. Use (?i) for a
+// case-insensitive rule.
+func NewRegex(name string, action Action, pattern string) (*Regex, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &Regex{name: name, action: action, pattern: re}, nil
+}
+
+// Check implements Filter.
+func (r *Regex) Check(text string) []Match {
+	matches := []Match{}
+	for _, loc := range r.pattern.FindAllStringIndex(text, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		matches = append(matches, Match{Rule: r.name, Action: r.action, Start: loc[0], End: loc[1]})
+	}
+	return matches
+}
+
+// linkPattern finds things that look like links, with or without a scheme,
+// such as "https://example.com/x" or "example.com".
+var linkPattern = regexp.MustCompile(`(?i)(?:https?://)?(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}(?::[0-9]+)?(?:/[^\s]*)?`)
+
+// LinkBlocklist matches links to blocked domains and their subdomains.
+type LinkBlocklist struct {
+	name    string
+	action  Action
+	domains map[string]bool
+}
+
+// NewLinkBlocklist returns a filter for links to domains.
+func NewLinkBlocklist(name string, action Action, domains []string) (*LinkBlocklist, error) {
+	l := &LinkBlocklist{name: name, action: action, domains: map[string]bool{}}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+		if domain == "" || strings.ContainsAny(domain, "/: ") {
+			return nil, fmt.Errorf("%q isn't a domain name", domain)
+		}
+		l.domains[domain] = true
+	}
+	return l, nil
+}
+
+// Check implements Filter.
+func (l *LinkBlocklist) Check(text string) []Match {
+	matches := []Match{}
+	for _, loc := range linkPattern.FindAllStringIndex(text, -1) {
+		// A match starting mid-word, like the "b.com" in "ab.com", is part
+		// of a longer name the pattern has already considered.
+		if r, _ := utf8.DecodeLastRuneInString(text[:loc[0]]); loc[0] > 0 && (isWordRune(r) || r == '.' || r == '-') {
+			continue
+		}
+		if l.blocked(linkHost(text[loc[0]:loc[1]])) {
+			matches = append(matches, Match{Rule: l.name, Action: l.action, Start: loc[0], End: loc[1]})
+		}
+	}
+	return matches
+}
+
+// blocked reports whether host is a blocked domain or a subdomain of one.
+func (l *LinkBlocklist) blocked(host string) bool {
+	for host != "" {
+		if l.domains[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return false
+		}
+		host = parent
+	}
+	return false
+}
+
+// linkHost returns the lower-cased host name of a link found by
+// linkPattern.
+func linkHost(link string) string {
+	link = strings.ToLower(link)
+	link = strings.TrimPrefix(link, "https://")
+	link = strings.TrimPrefix(link, "http://")
+	if i := strings.IndexByte(link, '/'); i >= 0 {
+		link = link[:i]
+	}
+	if host, _, err := net.SplitHostPort(link); err == nil {
+		link = host
+	}
+	return link
+}
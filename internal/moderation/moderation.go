@@ -0,0 +1,115 @@
+// Package moderation checks chirp text against configurable rules. Each
+// rule is a Filter that reports matches, and each match carries the action
+// its rule calls for: mask the text, reject the chirp, or flag it for a
+// moderator to review.
+package moderation
+
+import (
+	"sort"
+	"strings"
+)
+
+// Action is what happens to text that matches a rule.
+type Action string
+
+const (
+	// ActionMask replaces the matched text with Mask.
+	ActionMask Action = "mask"
+	// ActionReject refuses the text outright.
+	ActionReject Action = "reject"
+	// ActionFlag accepts the text unchanged but queues it for review.
+	ActionFlag Action = "flag"
+)
+
+// Mask is what masked text is replaced with.
+const Mask = "****"
+
+// valid reports whether a is a known action.
+func (a Action) valid() bool {
+	return a == ActionMask || a == ActionReject || a == ActionFlag
+}
+
+// Match is one place a rule matched. Start and End are byte offsets into
+// the checked text.
+type Match struct {
+	Rule   string
+	Action Action
+	Start  int
+	End    int
+}
+
+// Filter finds the parts of a text that break one rule.
+type Filter interface {
+	Check(text string) []Match
+}
+
+// Pipeline runs text through a chain of filters.
+type Pipeline struct {
+	filters []Filter
+}
+
+// NewPipeline returns a pipeline that applies filters in order.
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+// DefaultPipeline masks the words the server has always filtered. It is
+// used when no rules file is configured.
+func DefaultPipeline() *Pipeline {
+	words, _ := NewWordList("profanity", ActionMask, []string{"kerfuffle", "sharbert", "fornax"})
+	return NewPipeline(words)
+}
+
+// Result is the outcome of moderating a text.
+type Result struct {
+	// Text is the input with every masked match replaced by Mask.
+	Text    string
+	Matches []Match
+}
+
+// Matching returns the matches whose rule calls for action.
+func (r Result) Matching(action Action) []Match {
+	matches := []Match{}
+	for _, m := range r.Matches {
+		if m.Action == action {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// Moderate checks text against every filter and applies the masks.
+func (p *Pipeline) Moderate(text string) Result {
+	result := Result{Text: text, Matches: []Match{}}
+	for _, f := range p.filters {
+		result.Matches = append(result.Matches, f.Check(text)...)
+	}
+	result.Text = mask(text, result.Matching(ActionMask))
+	return result
+}
+
+// mask replaces the spans of matches in text with Mask, merging spans that
+// overlap so each stretch of masked text becomes a single Mask.
+func mask(text string, matches []Match) string {
+	if len(matches) == 0 {
+		return text
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+
+	var b strings.Builder
+	pos := 0
+	for _, m := range matches {
+		if m.End <= pos {
+			continue
+		}
+		if m.Start >= pos {
+			b.WriteString(text[pos:m.Start])
+			b.WriteString(Mask)
+		}
+		pos = m.End
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
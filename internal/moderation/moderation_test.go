@@ -0,0 +1,173 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// This test ensures the default word list masks words however they are dressed up
+func TestDefaultPipeline(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "Clean", text: "I had something interesting for breakfast", want: "I had something interesting for breakfast"},
+		{name: "Plain word", text: "This is a kerfuffle opinion", want: "This is a **** opinion"},
+		{name: "Trailing punctuation", text: "What a kerfuffle!", want: "What a ****!"},
+		{name: "Capitalised with comma", text: "Sharbert, please", want: "****, please"},
+		{name: "Accented", text: "fórnax", want: "****"},
+		{name: "Decomposed accent", text: "fo\u0301rnax", want: "****"},
+		{name: "Fullwidth", text: "ｋｅｒｆｕｆｆｌｅ", want: "****"},
+		{name: "Part of a longer word", text: "kerfuffles", want: "kerfuffles"},
+		{name: "Several", text: "kerfuffle and Fornax.", want: "**** and ****."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultPipeline().Moderate(tt.text).Text
+			if got != tt.want {
+				t.Errorf("Moderate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// This test ensures links are matched by domain and its subdomains only
+func TestLinkBlocklist(t *testing.T) {
+	filter, err := NewLinkBlocklist("spam", ActionReject, []string{"spam.example"})
+	if err != nil {
+		t.Fatalf("NewLinkBlocklist() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "Bare domain", text: "go to spam.example now", want: 1},
+		{name: "With scheme and path", text: "https://spam.example/win?x=1", want: 1},
+		{name: "Uppercase", text: "SPAM.EXAMPLE", want: 1},
+		{name: "Subdomain", text: "http://www.spam.example", want: 1},
+		{name: "With port", text: "spam.example:8080/x", want: 1},
+		{name: "Lookalike domain", text: "notspam.example", want: 0},
+		{name: "Other domain", text: "https://example.com", want: 0},
+		{name: "No link", text: "nothing to see", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(filter.Check(tt.text)); got != tt.want {
+				t.Errorf("Check() found %d matches, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// This test ensures each action does what it says and overlapping masks merge
+func TestPipelineActions(t *testing.T) {
+	words, _ := NewWordList("words", ActionMask, []string{"fornax"})
+	phone, _ := NewRegex("phone", ActionFlag, `\d{3}-\d{4}`)
+	shout, _ := NewRegex("shout", ActionMask, `(?i)fornax!+`)
+	links, _ := NewLinkBlocklist("links", ActionReject, []string{"spam.example"})
+	pipeline := NewPipeline(words, phone, shout, links)
+
+	result := pipeline.Moderate("fornax!! call 555-1234")
+	if result.Text != "**** call 555-1234" {
+		t.Errorf("Text = %q, want %q", result.Text, "**** call 555-1234")
+	}
+	if flags := result.Matching(ActionFlag); len(flags) != 1 || flags[0].Rule != "phone" {
+		t.Errorf("Matching(flag) = %v, want one phone match", flags)
+	}
+	if rejects := result.Matching(ActionReject); len(rejects) != 0 {
+		t.Errorf("Matching(reject) = %v, want none", rejects)
+	}
+
+	result = pipeline.Moderate("see spam.example")
+	if rejects := result.Matching(ActionReject); len(rejects) != 1 || rejects[0].Rule != "links" {
+		t.Errorf("Matching(reject) = %v, want one links match", rejects)
+	}
+}
+
+// This test ensures bad rules files are refused with an error
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "Valid", config: `{"rules": [
+			{"name": "w", "type": "words", "action": "mask", "words": ["fornax"]},
+			{"name": "r", "type": "regex", "action": "flag", "pattern": "a+"},
+			{"name": "l", "type": "links", "action": "reject", "domains": ["spam.example"]}
+		]}`},
+		{name: "Empty", config: `{"rules": []}`},
+		{name: "Unknown type", config: `{"rules": [{"name": "x", "type": "magic", "action": "mask"}]}`, wantErr: true},
+		{name: "Unknown action", config: `{"rules": [{"name": "x", "type": "words", "action": "ban", "words": ["a"]}]}`, wantErr: true},
+		{name: "Missing name", config: `{"rules": [{"type": "words", "action": "mask", "words": ["a"]}]}`, wantErr: true},
+		{name: "Duplicate name", config: `{"rules": [
+			{"name": "x", "type": "words", "action": "mask", "words": ["a"]},
+			{"name": "x", "type": "words", "action": "mask", "words": ["b"]}
+		]}`, wantErr: true},
+		{name: "Phrase in word list", config: `{"rules": [{"name": "x", "type": "words", "action": "mask", "words": ["two words"]}]}`, wantErr: true},
+		{name: "Bad regex", config: `{"rules": [{"name": "x", "type": "regex", "action": "mask", "pattern": "("}]}`, wantErr: true},
+		{name: "Unknown field", config: `{"rules": [{"name": "x", "type": "words", "action": "mask", "wrods": ["a"]}]}`, wantErr: true},
+		{name: "Not JSON", config: `rules:`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// This test ensures the watcher picks up edits and keeps the old rules when an edit is broken
+func TestWatcherReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moderation.json")
+	write := func(config string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// Set the time explicitly; edits within the filesystem's timestamp
+		// resolution would otherwise look unchanged.
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+
+	write(`{"rules": [{"name": "w", "type": "words", "action": "mask", "words": ["fornax"]}]}`, now)
+	watcher, err := NewWatcher(path)
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	if got := watcher.Pipeline().Moderate("fornax sharbert").Text; got != "**** sharbert" {
+		t.Fatalf("Moderate() = %q, want %q", got, "**** sharbert")
+	}
+
+	if changed, err := watcher.Reload(); changed || err != nil {
+		t.Errorf("Reload() of an unchanged file = %v, %v, want false, nil", changed, err)
+	}
+
+	write(`{"rules": [{"name": "w", "type": "words", "action": "mask", "words": ["sharbert"]}]}`, now.Add(time.Second))
+	if changed, err := watcher.Reload(); !changed || err != nil {
+		t.Fatalf("Reload() = %v, %v, want true, nil", changed, err)
+	}
+	if got := watcher.Pipeline().Moderate("fornax sharbert").Text; got != "fornax ****" {
+		t.Errorf("Moderate() = %q, want %q", got, "fornax ****")
+	}
+
+	write(`{"rules": [`, now.Add(2*time.Second))
+	if _, err := watcher.Reload(); err == nil {
+		t.Errorf("Reload() of a broken file error = nil, want an error")
+	}
+	if got := watcher.Pipeline().Moderate("fornax sharbert").Text; got != "fornax ****" {
+		t.Errorf("Moderate() after a broken edit = %q, want %q", got, "fornax ****")
+	}
+}
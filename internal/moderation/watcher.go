@@ -0,0 +1,98 @@
+package moderation
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Source provides the pipeline currently in force.
+type Source interface {
+	Pipeline() *Pipeline
+}
+
+// Static returns a Source that always provides p.
+func Static(p *Pipeline) Source {
+	return staticSource{p}
+}
+
+type staticSource struct {
+	p *Pipeline
+}
+
+func (s staticSource) Pipeline() *Pipeline {
+	return s.p
+}
+
+// Watcher is a Source backed by a rules file, which it reloads when the
+// file changes. A file that fails to load leaves the previous rules in
+// force.
+type Watcher struct {
+	path    string
+	current atomic.Pointer[Pipeline]
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher loads the rules file at path. Call Watch to pick up changes.
+func NewWatcher(path string) (*Watcher, error) {
+	w := &Watcher{path: path}
+	if _, err := w.Reload(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Pipeline implements Source.
+func (w *Watcher) Pipeline() *Pipeline {
+	return w.current.Load()
+}
+
+// Reload reloads the rules file if it changed since it was last loaded, and
+// reports whether it did.
+func (w *Watcher) Reload() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false, err
+	}
+	if w.current.Load() != nil && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false, nil
+	}
+
+	// Remember the attempt even if it fails, so a broken file is reported
+	// once rather than on every check.
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+	pipeline, err := LoadFile(w.path)
+	if err != nil {
+		return false, err
+	}
+	w.current.Store(pipeline)
+	return true, nil
+}
+
+// Watch checks the rules file every interval until ctx is done, calling
+// onReload after each reload attempt that changed the rules or failed.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration, onReload func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := w.Reload()
+		if (changed || err != nil) && onReload != nil {
+			onReload(err)
+		}
+	}
+}
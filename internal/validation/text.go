@@ -0,0 +1,80 @@
+// Package validation checks and normalizes user-written text before it is
+// stored.
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Text rule names reported in TextError.Rule.
+const (
+	RuleInvalidUTF8 = "invalid_utf8"
+	RuleControl     = "control_character"
+	RuleEmpty       = "empty"
+	RuleMaxLength   = "max_length"
+)
+
+// TextPolicy describes what a piece of free text, such as a chirp body,
+// may contain.
+type TextPolicy struct {
+	// MaxLength counts grapheme clusters, the characters a reader sees, so
+	// an emoji with skin tone or a flag counts once. Zero disables the
+	// check.
+	MaxLength int
+	// AllowNewlines permits line breaks inside the text. Tabs are always
+	// allowed; every other control character is rejected.
+	AllowNewlines bool
+}
+
+// TextError is the first rule a piece of text failed. Message is written to
+// follow the name of the field, as in "Chirp must not be empty".
+type TextError struct {
+	Rule    string
+	Message string
+}
+
+func (e *TextError) Error() string {
+	return e.Message
+}
+
+// Clean validates text and returns it in the form to store: NFC-normalized,
+// with CRLF line endings turned into LF and surrounding whitespace trimmed.
+// Length is checked after normalizing, so the same text typed with
+// precomposed or combining accents gets the same answer. It returns a
+// *TextError when text breaks the policy.
+func (p TextPolicy) Clean(text string) (string, error) {
+	if !utf8.ValidString(text) {
+		return "", &TextError{Rule: RuleInvalidUTF8, Message: "must be valid UTF-8"}
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = norm.NFC.String(text)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", &TextError{Rule: RuleEmpty, Message: "must not be empty"}
+	}
+
+	for _, r := range text {
+		if !unicode.IsControl(r) || r == '\t' || (r == '\n' && p.AllowNewlines) {
+			continue
+		}
+		return "", &TextError{
+			Rule:    RuleControl,
+			Message: fmt.Sprintf("must not contain control character %U", r),
+		}
+	}
+
+	if p.MaxLength > 0 && uniseg.GraphemeClusterCount(text) > p.MaxLength {
+		return "", &TextError{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("must be at most %d characters", p.MaxLength),
+		}
+	}
+	return text, nil
+}
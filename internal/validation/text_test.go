@@ -0,0 +1,152 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// This test ensures text is normalized and checked the way a reader would
+// count it, not byte by byte
+func TestTextPolicyClean(t *testing.T) {
+	policy := TextPolicy{MaxLength: 10, AllowNewlines: true}
+
+	tests := []struct {
+		name     string
+		policy   TextPolicy
+		text     string
+		want     string
+		wantRule string
+	}{
+		{
+			name:   "Plain text",
+			policy: policy,
+			text:   "hello",
+			want:   "hello",
+		},
+		{
+			name:   "Surrounding whitespace is trimmed",
+			policy: policy,
+			text:   " \t hello \n",
+			want:   "hello",
+		},
+		{
+			name:     "Empty",
+			policy:   policy,
+			text:     "",
+			wantRule: RuleEmpty,
+		},
+		{
+			name:     "Only whitespace",
+			policy:   policy,
+			text:     " \n\t ",
+			wantRule: RuleEmpty,
+		},
+		{
+			name:     "Invalid UTF-8",
+			policy:   policy,
+			text:     "caf\xe9",
+			wantRule: RuleInvalidUTF8,
+		},
+		{
+			name:   "Combining accents are composed",
+			policy: policy,
+			text:   "cafe\u0301",
+			want:   "caf\u00e9",
+		},
+		{
+			name:   "Combining marks don't count as characters",
+			policy: policy,
+			text:   strings.Repeat("a\u0301\u0323", 10),
+			want:   strings.Repeat("\u1ea1\u0301", 10),
+		},
+		{
+			name:   "Emoji count once each",
+			policy: policy,
+			text:   strings.Repeat("😀", 10),
+			want:   strings.Repeat("😀", 10),
+		},
+		{
+			name:   "Multi-rune graphemes count once each",
+			policy: policy,
+			text:   strings.Repeat("👍🏽", 5) + strings.Repeat("🇳🇿", 5),
+			want:   strings.Repeat("👍🏽", 5) + strings.Repeat("🇳🇿", 5),
+		},
+		{
+			name:     "Too long",
+			policy:   policy,
+			text:     strings.Repeat("😀", 11),
+			wantRule: RuleMaxLength,
+		},
+		{
+			name:   "Length is measured after trimming",
+			policy: policy,
+			text:   "   0123456789   ",
+			want:   "0123456789",
+		},
+		{
+			name:   "No limit",
+			policy: TextPolicy{},
+			text:   strings.Repeat("a", 1000),
+			want:   strings.Repeat("a", 1000),
+		},
+		{
+			name:   "CRLF becomes LF",
+			policy: policy,
+			text:   "one\r\ntwo",
+			want:   "one\ntwo",
+		},
+		{
+			name:     "Newlines when not allowed",
+			policy:   TextPolicy{MaxLength: 10},
+			text:     "one\ntwo",
+			wantRule: RuleControl,
+		},
+		{
+			name:   "Tabs are allowed",
+			policy: TextPolicy{MaxLength: 10},
+			text:   "one\ttwo",
+			want:   "one\ttwo",
+		},
+		{
+			name:     "NUL",
+			policy:   policy,
+			text:     "a\x00b",
+			wantRule: RuleControl,
+		},
+		{
+			name:     "Escape",
+			policy:   policy,
+			text:     "\x1b[31mred",
+			wantRule: RuleControl,
+		},
+		{
+			name:     "C1 control",
+			policy:   policy,
+			text:     "a\u0085b",
+			wantRule: RuleControl,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Clean(tt.text)
+			if tt.wantRule != "" {
+				var textErr *TextError
+				if !errors.As(err, &textErr) {
+					t.Fatalf("Clean() error = %v, want rule %s", err, tt.wantRule)
+				}
+				if textErr.Rule != tt.wantRule {
+					t.Errorf("Clean() rule = %s, want %s", textErr.Rule, tt.wantRule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Clean() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Clean() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
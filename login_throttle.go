@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+)
+
+// loginThrottle decides how long to block further logins after repeated
+// failures. The first few failures are free; after that each failure doubles
+// the wait, and once lockoutAfter failures pile up the key is locked for
+// lockoutDuration. Failures older than resetAfter are forgotten.
+type loginThrottle struct {
+	freeAttempts    int
+	baseDelay       time.Duration
+	maxDelay        time.Duration
+	lockoutAfter    int
+	lockoutDuration time.Duration
+	resetAfter      time.Duration
+}
+
+// blockFor returns how long to refuse logins after the given number of
+// consecutive failures.
+func (t loginThrottle) blockFor(failures int) time.Duration {
+	if t.lockoutAfter > 0 && failures >= t.lockoutAfter {
+		return t.lockoutDuration
+	}
+	if failures <= t.freeAttempts {
+		return 0
+	}
+	exp := float64(failures - t.freeAttempts - 1)
+	delay := time.Duration(float64(t.baseDelay) * math.Pow(2, exp))
+	if delay > t.maxDelay || delay <= 0 {
+		return t.maxDelay
+	}
+	return delay
+}
+
+// loginThrottles holds the policy for each kind of key. Accounts lock out;
+// IP addresses only slow down, so a shared NAT can't be used to lock every
+// user behind it out.
+type loginThrottles struct {
+	email loginThrottle
+	ip    loginThrottle
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// clientIP returns the address of the client that sent r. Behind a reverse
+// proxy, set TRUST_PROXY_HEADERS so the address the proxy appended to
+// X-Forwarded-For is used instead of the proxy's own.
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	if cfg.trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			return strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// throttledKey is a key to count a login attempt against, with the policy
+// for it.
+type throttledKey struct {
+	key      string
+	throttle loginThrottle
+}
+
+// errLoginBlocked rolls back beginLoginAttempt's transaction when a key is
+// already blocked.
+var errLoginBlocked = errors.New("login blocked")
+
+// beginLoginAttempt counts an attempt against each of keys before its
+// credentials are checked, and blocks each key for as long as its throttle
+// asks in case they turn out to be wrong. Both happen in one transaction
+// that holds the keys' rows, so a burst of concurrent guesses is counted one
+// guess at a time instead of all getting past the check together. Once the
+// credentials are known to be right, call forgiveLoginAttempt.
+//
+// If any key is already blocked nothing is counted, and it returns how much
+// longer the block lasts.
+func (cfg *apiConfig) beginLoginAttempt(ctx context.Context, keys ...throttledKey) (time.Duration, error) {
+	var wait time.Duration
+	counts := make([]int, len(keys))
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		for i, k := range keys {
+			attempt, err := q.RecordLoginAttempt(ctx, database.RecordLoginAttemptParams{
+				Key:         k.key,
+				ResetBefore: time.Now().Add(-k.throttle.resetAfter),
+			})
+			if err != nil {
+				return err
+			}
+			if attempt.BlockedUntil.Valid {
+				if remaining := time.Until(attempt.BlockedUntil.Time); remaining > wait {
+					wait = remaining
+				}
+			}
+			counts[i] = int(attempt.FailedCount)
+		}
+		if wait > 0 {
+			return errLoginBlocked
+		}
+
+		for i, k := range keys {
+			delay := k.throttle.blockFor(counts[i])
+			if delay == 0 {
+				continue
+			}
+			err := q.BlockLoginAttempts(ctx, database.BlockLoginAttemptsParams{
+				Key:          k.key,
+				BlockedUntil: sql.NullTime{Time: time.Now().Add(delay), Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errLoginBlocked) {
+		return wait, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	for i, k := range keys {
+		if k.throttle.lockoutAfter > 0 && counts[i] >= k.throttle.lockoutAfter {
+			log.Printf("Locking out %s after %d failed logins", k.key, counts[i])
+		}
+	}
+	return 0, nil
+}
+
+// forgiveLoginAttempt takes back the attempt beginLoginAttempt counted
+// against each of keys, once its credentials turned out to be right.
+func (cfg *apiConfig) forgiveLoginAttempt(ctx context.Context, keys ...throttledKey) {
+	for _, k := range keys {
+		if err := cfg.db.ForgiveLoginAttempt(ctx, k.key); err != nil {
+			log.Printf("Couldn't forgive login attempt for %s: %s", k.key, err)
+		}
+	}
+}
+
+// respondLoginBlocked tells the client to come back after wait.
+func respondLoginBlocked(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	respondWithError(w, http.StatusTooManyRequests, "Too many failed login attempts, try again later", nil)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// This test ensures failures past the free attempts back off exponentially,
+// up to the cap, and lock out at the threshold
+func TestLoginThrottleBlockFor(t *testing.T) {
+	account := loginThrottle{
+		freeAttempts:    3,
+		baseDelay:       time.Second,
+		maxDelay:        time.Minute,
+		lockoutAfter:    10,
+		lockoutDuration: 15 * time.Minute,
+	}
+	ip := loginThrottle{
+		freeAttempts: 3,
+		baseDelay:    time.Second,
+		maxDelay:     5 * time.Minute,
+	}
+
+	tests := []struct {
+		name     string
+		throttle loginThrottle
+		failures int
+		want     time.Duration
+	}{
+		{
+			name:     "No failures",
+			throttle: account,
+			failures: 0,
+			want:     0,
+		},
+		{
+			name:     "Last free failure",
+			throttle: account,
+			failures: 3,
+			want:     0,
+		},
+		{
+			name:     "First failure past the free ones",
+			throttle: account,
+			failures: 4,
+			want:     time.Second,
+		},
+		{
+			name:     "Each further failure doubles the wait",
+			throttle: account,
+			failures: 6,
+			want:     4 * time.Second,
+		},
+		{
+			name:     "Last failure before lockout",
+			throttle: account,
+			failures: 9,
+			want:     32 * time.Second,
+		},
+		{
+			name:     "Lockout at the threshold",
+			throttle: account,
+			failures: 10,
+			want:     15 * time.Minute,
+		},
+		{
+			name:     "Lockout past the threshold",
+			throttle: account,
+			failures: 25,
+			want:     15 * time.Minute,
+		},
+		{
+			name:     "No lockout without a threshold",
+			throttle: ip,
+			failures: 12,
+			want:     256 * time.Second,
+		},
+		{
+			name:     "Cap without a threshold",
+			throttle: ip,
+			failures: 13,
+			want:     5 * time.Minute,
+		},
+		{
+			name:     "Overflowing wait is capped",
+			throttle: ip,
+			failures: 1000,
+			want:     5 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.throttle.blockFor(tt.failures); got != tt.want {
+				t.Errorf("blockFor(%d) = %s, want %s", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+// This test ensures X-Forwarded-For is only believed behind a trusted proxy,
+// and then only the hop the proxy added
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{
+			name:       "RemoteAddr with port",
+			remoteAddr: "203.0.113.7:51234",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "IPv6 RemoteAddr with port",
+			remoteAddr: "[2001:db8::1]:51234",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "RemoteAddr without a port",
+			remoteAddr: "203.0.113.7",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Forwarded header ignored without a trusted proxy",
+			remoteAddr: "203.0.113.7:51234",
+			forwarded:  "198.51.100.9",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Forwarded address behind a trusted proxy",
+			trustProxy: true,
+			remoteAddr: "10.0.0.2:51234",
+			forwarded:  "198.51.100.9",
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "Client-supplied hops are skipped",
+			trustProxy: true,
+			remoteAddr: "10.0.0.2:51234",
+			forwarded:  "192.0.2.1, 192.0.2.2,198.51.100.9",
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "No header behind a trusted proxy",
+			trustProxy: true,
+			remoteAddr: "10.0.0.2:51234",
+			want:       "10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &apiConfig{trustProxy: tt.trustProxy}
+			r := httptest.NewRequest("GET", "/api/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := cfg.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,54 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
 	"sync/atomic"
+	"time"
 
+	"github.com/ItSpecOps/go-server/internal/auth"
 	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/ItSpecOps/go-server/internal/mailer"
+	"github.com/ItSpecOps/go-server/internal/moderation"
+	"github.com/ItSpecOps/go-server/internal/validation"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	dbConn         *sql.DB
 	db             *database.Queries
 	platform       string
-	jwtSecret     string
+	keyring        *auth.Keyring
+	accessTokens   auth.ValidatorOptions
+	revocations    *tokenRevocations
+	passwords      auth.PasswordHasher
+	passwordPolicy auth.PasswordPolicy
+	chirpPolicy    validation.TextPolicy
+	loginThrottles loginThrottles
+	mailer         mailer.Mailer
+	moderation     moderation.Source
+	// verifyEmailURL, resetURL and magicLinkURL are the frontend pages
+	// emails link to, if any.
+	verifyEmailURL string
+	resetURL       string
+	magicLinkURL   string
+	// trustProxy makes clientIP believe X-Forwarded-For.
+	trustProxy bool
+	// adminsNeed2FA keeps admins who haven't enrolled in two-factor out of
+	// admin routes.
+	adminsNeed2FA bool
+	// deletionGrace is how long a deleted account can still be restored.
+	deletionGrace time.Duration
+	// magicLinks turns on passwordless login by emailed link.
+	magicLinks bool
+	// editWindow is how long after posting a chirp its author can
+	// edit it. Zero turns editing off.
+	editWindow time.Duration
 }
 
 func main() {
@@ -32,9 +64,62 @@ func main() {
 	if platform == "" {
 		log.Fatal("PLATFORM must be set")
 	}
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET must be set")
+	keyring, err := loadKeyring()
+	if err != nil {
+		log.Fatalf("Error loading signing keys: %s", err)
+	}
+
+	passwords, err := loadPasswordHasher()
+	if err != nil {
+		log.Fatalf("Error loading password hashing settings: %s", err)
+	}
+
+	passwordPolicy, err := loadPasswordPolicy()
+	if err != nil {
+		log.Fatalf("Error loading password policy: %s", err)
+	}
+
+	throttles, err := loadLoginThrottles()
+	if err != nil {
+		log.Fatalf("Error loading login throttling settings: %s", err)
+	}
+	trustProxy, err := envBool("TRUST_PROXY_HEADERS", false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mail, err := loadMailer(platform)
+	if err != nil {
+		log.Fatalf("Error loading mailer: %s", err)
+	}
+
+	chirpPolicy, err := loadChirpPolicy()
+	if err != nil {
+		log.Fatalf("Error loading chirp limits: %s", err)
+	}
+
+	moderationRules, err := loadModeration()
+	if err != nil {
+		log.Fatalf("Error loading moderation rules: %s", err)
+	}
+
+	deletionGrace, err := envDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	adminsNeed2FA, err := envBool("ADMIN_REQUIRE_2FA", platform != "dev")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	magicLinks, err := envBool("MAGIC_LINK_ENABLED", true)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	chirpEditWindow, err := envDuration("CHIRP_EDIT_WINDOW", time.Hour)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	dbConn, err := sql.Open("postgres", dbURL)
@@ -42,34 +127,127 @@ func main() {
 		log.Fatalf("Error opening database: %s", err)
 	}
 	dbQueries := database.New(dbConn)
+	revocations := newTokenRevocations(dbQueries)
 
 	apiCfg := apiConfig{
 		fileserverHits: atomic.Int32{},
+		dbConn:         dbConn,
 		db:             dbQueries,
 		platform:       platform,
-		jwtSecret:     jwtSecret,
+		keyring:        keyring,
+		accessTokens:   accessTokenOptions(keyring, revocations),
+		revocations:    revocations,
+		passwords:      passwords,
+		passwordPolicy: passwordPolicy,
+		chirpPolicy:    chirpPolicy,
+		loginThrottles: throttles,
+		deletionGrace:  deletionGrace,
+		mailer:         mail,
+		moderation:     moderationRules,
+		verifyEmailURL: os.Getenv("VERIFY_EMAIL_URL"),
+		resetURL:       os.Getenv("RESET_PASSWORD_URL"),
+		magicLinkURL:   os.Getenv("MAGIC_LINK_URL"),
+		trustProxy:     trustProxy,
+		adminsNeed2FA:  adminsNeed2FA,
+		magicLinks:     magicLinks,
+		editWindow:     chirpEditWindow,
 	}
 
+	go apiCfg.purgeDeletedAccounts(context.Background(), accountPurgeInterval)
+
 	mux := http.NewServeMux()
 	fsHandler := apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
 	mux.Handle("/app/", fsHandler)
 
 	mux.HandleFunc("GET /api/healthz", handlerReadiness)
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.handlerJWKS)
+
+	// authed routes need an access token granted scope; admin routes need
+	// an admin's token with the admin scope, and outside dev an admin who
+	// has enrolled in two-factor.
+	authed := func(scope string, h http.HandlerFunc) http.Handler {
+		return apiCfg.middlewareRequireAuth(apiCfg.middlewareRequireScope(scope, h))
+	}
+	// verified routes also need the caller to have verified their email.
+	verified := func(scope string, h http.HandlerFunc) http.Handler {
+		return authed(scope, apiCfg.middlewareRequireVerifiedEmail(h).ServeHTTP)
+	}
+	admin := func(h http.HandlerFunc) http.Handler {
+		var next http.Handler = h
+		if apiCfg.adminsNeed2FA {
+			next = apiCfg.middlewareRequireTwoFactor(next)
+		}
+		return apiCfg.middlewareRequireAuth(apiCfg.middlewareRequireRole(auth.RoleAdmin, next))
+	}
+	// moderator routes need the moderator role and the moderate scope, so
+	// API keys without it can't review flags.
+	moderator := func(h http.HandlerFunc) http.Handler {
+		return authed(auth.ScopeModerate, apiCfg.middlewareRequireRole(auth.RoleModerator, h).ServeHTTP)
+	}
 
 	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
+	mux.HandleFunc("POST /api/login/2fa", apiCfg.handlerLoginTwoFactor)
+	if apiCfg.magicLinks {
+		mux.HandleFunc("POST /api/login/magic", apiCfg.handlerLoginMagic)
+		mux.HandleFunc("POST /api/login/magic/redeem", apiCfg.handlerLoginMagicRedeem)
+	}
 	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
 
+	mux.Handle("GET /api/sessions", authed(auth.ScopeAccount, apiCfg.handlerSessionsList))
+	mux.Handle("DELETE /api/sessions", authed(auth.ScopeAccount, apiCfg.handlerSessionsDeleteAll))
+	mux.Handle("DELETE /api/sessions/{sessionID}", authed(auth.ScopeAccount, apiCfg.handlerSessionsDelete))
+
+	mux.HandleFunc("POST /api/password/forgot", apiCfg.handlerPasswordForgot)
+	mux.HandleFunc("POST /api/password/reset", apiCfg.handlerPasswordReset)
+
 	mux.HandleFunc("POST /api/users", apiCfg.handlerUsersCreate)
-	mux.HandleFunc("PUT /api/users", apiCfg.handlerUsersUpdate)
+	mux.Handle("PATCH /api/users", authed(auth.ScopeAccount, apiCfg.handlerUsersUpdate))
+	// PUT predates partial updates and is kept for existing clients.
+	mux.Handle("PUT /api/users", authed(auth.ScopeAccount, apiCfg.handlerUsersUpdate))
+	mux.Handle("DELETE /api/users/me", authed(auth.ScopeAccount, apiCfg.handlerUsersDeleteMe))
+	mux.HandleFunc("POST /api/users/restore", apiCfg.handlerUsersRestore)
+	mux.Handle("GET /api/users/me/export", authed(auth.ScopeAccount, apiCfg.handlerUsersExport))
+	mux.HandleFunc("GET /api/users/{handle}", apiCfg.handlerUsersProfileGet)
+	mux.Handle("PATCH /api/users/me/profile", authed(auth.ScopeAccount, apiCfg.handlerUsersProfileUpdate))
+	mux.HandleFunc("POST /api/users/verify", apiCfg.handlerUsersVerify)
+	mux.Handle("POST /api/users/verify/resend", authed(auth.ScopeAccount, apiCfg.handlerUsersVerifyResend))
+	mux.Handle("POST /api/users/2fa/totp", authed(auth.ScopeAccount, apiCfg.handlerTOTPEnroll))
+	mux.Handle("POST /api/users/2fa/totp/confirm", authed(auth.ScopeAccount, apiCfg.handlerTOTPConfirm))
+	mux.Handle("DELETE /api/users/2fa/totp", authed(auth.ScopeAccount, apiCfg.handlerTOTPDisable))
 
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerChirpsCreate)
-	mux.HandleFunc("GET /api/chirps", apiCfg.handlerChirpsGet)
+	mux.Handle("POST /api/keys", authed(auth.ScopeAccount, apiCfg.handlerAPIKeysCreate))
+	mux.Handle("GET /api/keys", authed(auth.ScopeAccount, apiCfg.handlerAPIKeysList))
+	mux.Handle("DELETE /api/keys/{keyID}", authed(auth.ScopeAccount, apiCfg.handlerAPIKeysDelete))
+
+	mux.Handle("POST /api/oauth/clients", verified(auth.ScopeAccount, apiCfg.handlerOAuthClientsCreate))
+	mux.Handle("GET /api/oauth/clients", authed(auth.ScopeAccount, apiCfg.handlerOAuthClientsList))
+	mux.Handle("DELETE /api/oauth/clients/{clientID}", authed(auth.ScopeAccount, apiCfg.handlerOAuthClientsDelete))
+	mux.Handle("GET /api/oauth/authorize", authed(auth.ScopeAccount, apiCfg.handlerOAuthAuthorizeGet))
+	mux.Handle("POST /api/oauth/authorize", authed(auth.ScopeAccount, apiCfg.handlerOAuthAuthorize))
+	mux.HandleFunc("POST /oauth/token", apiCfg.handlerOAuthToken)
+	mux.HandleFunc("POST /oauth/revoke", apiCfg.handlerOAuthRevoke)
+	mux.HandleFunc("POST /oauth/introspect", apiCfg.handlerOAuthIntrospect)
+
+	mux.Handle("POST /api/chirps", verified(auth.ScopeChirpsWrite, apiCfg.handlerChirpsCreate))
+	mux.HandleFunc("GET /api/chirps", apiCfg.handlerChirpsRetrieve)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerChirpsGet)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerChirpsDelete)
+	mux.Handle("DELETE /api/chirps/{chirpID}", authed(auth.ScopeChirpsWrite, apiCfg.handlerChirpsDelete))
+	if apiCfg.editWindow > 0 {
+		mux.Handle("PUT /api/chirps/{chirpID}", verified(auth.ScopeChirpsWrite, apiCfg.handlerChirpsUpdate))
+	}
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.handlerChirpsRevisions)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.handlerChirpsThread)
 
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
+	mux.Handle("GET /api/moderation/flags", moderator(apiCfg.handlerModerationFlagsList))
+	mux.Handle("POST /api/moderation/flags/{flagID}/resolve", moderator(apiCfg.handlerModerationFlagsResolve))
+
+	mux.Handle("POST /admin/reset", admin(apiCfg.handlerReset))
+	mux.Handle("GET /admin/metrics", admin(apiCfg.handlerMetrics))
+	mux.Handle("PUT /admin/users/{userID}/role", admin(apiCfg.handlerAdminUsersUpdateRole))
+	mux.Handle("DELETE /admin/users/{userID}/lockout", admin(apiCfg.handlerAdminUsersUnlock))
+	mux.Handle("DELETE /admin/users/{userID}/sessions", admin(apiCfg.handlerAdminUsersRevokeSessions))
+	mux.Handle("POST /admin/tokens/revoke", admin(apiCfg.handlerAdminTokensRevoke))
 
 	srv := &http.Server{
 		Addr:    ":" + port,
@@ -78,4 +256,4 @@ func main() {
 
 	log.Printf("Serving on port: %s\n", port)
 	log.Fatal(srv.ListenAndServe())
-}
\ No newline at end of file
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/google/uuid"
+)
+
+// middlewareRequireAuth rejects requests without a valid access token or
+// API key and makes the caller available to next through requestPrincipal.
+func (cfg *apiConfig) middlewareRequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, credentials, err := auth.GetAuthorization(r.Header)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+			return
+		}
+
+		var principal auth.Principal
+		switch scheme {
+		case auth.SchemeBearer:
+			principal, err = cfg.authenticate(r.Context(), credentials)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+				return
+			}
+		case auth.SchemeAPIKey:
+			principal, err = cfg.authenticateAPIKey(r.Context(), credentials)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", auth.SchemeAPIKey)
+				respondWithError(w, http.StatusUnauthorized, "Couldn't validate API key", err)
+				return
+			}
+		default:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondWithError(w, http.StatusUnauthorized, "Unsupported authorization scheme "+scheme, nil)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// authenticate validates an access token and returns its principal.
+// Revoked tokens, including those from a session that has since been ended,
+// are rejected.
+func (cfg *apiConfig) authenticate(ctx context.Context, token string) (auth.Principal, error) {
+	claims, err := cfg.keyring.ValidateJWTContext(ctx, token, cfg.accessTokens)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	var sessionID uuid.UUID
+	if claims.SessionID != "" {
+		sessionID, err = uuid.Parse(claims.SessionID)
+		if err != nil {
+			return auth.Principal{}, fmt.Errorf("invalid session ID: %w", err)
+		}
+	}
+
+	return auth.Principal{
+		UserID:    claims.UserID,
+		Role:      claims.Role,
+		Scopes:    claims.Scopes(),
+		TokenID:   claims.ID,
+		SessionID: sessionID,
+	}, nil
+}
+
+// requestPrincipal returns the caller stored by middlewareRequireAuth. ok is
+// false outside that middleware.
+func requestPrincipal(r *http.Request) (principal auth.Principal, ok bool) {
+	return auth.PrincipalFromContext(r.Context())
+}
+
+// middlewareRequireRole only lets through callers holding role. It must be
+// wrapped by middlewareRequireAuth.
+func (cfg *apiConfig) middlewareRequireRole(role auth.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(r)
+		if !ok || !principal.HasRole(role) {
+			respondWithError(w, http.StatusForbidden, "You don't have permission to do that", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// middlewareRequireScope only lets through callers whose token was granted
+// scope. It must be wrapped by middlewareRequireAuth.
+func (cfg *apiConfig) middlewareRequireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(r)
+		if !ok || !principal.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+			respondWithError(w, http.StatusForbidden, "Token is missing the "+scope+" scope", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
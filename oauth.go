@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// oauthCodeTTL is how long an authorization code can be exchanged for
+	// tokens.
+	oauthCodeTTL = 5 * time.Minute
+
+	maxOAuthClientNameLength = 100
+	maxOAuthRedirectURIs     = 10
+)
+
+// oauthGrantableScopes are the scopes third-party clients may ask for, in
+// the order they are listed. Account management, moderation and admin stay
+// first-party.
+var oauthGrantableScopes = []string{auth.ScopeChirpsRead, auth.ScopeChirpsWrite}
+
+// OAuthClient is a registered third-party app as shown to its owner. The
+// secret is only ever returned when the client is created.
+type OAuthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	// Confidential clients authenticate with a secret; public ones rely on
+	// PKCE alone.
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// oauthClientFromDB converts a database row into its API representation.
+func oauthClientFromDB(client database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Confidential: client.SecretHash.Valid,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// validRedirectURI reports whether raw may be registered as a redirect URI:
+// an absolute https URL, or http on a loopback address for native apps,
+// without a fragment.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Fragment != "" || u.User != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	default:
+		return false
+	}
+}
+
+// parseOAuthScope checks a space-separated scope request against the
+// grantable scopes and returns it normalised. An empty request gets
+// chirps:read.
+func parseOAuthScope(scope string) (string, error) {
+	requested := map[string]bool{}
+	for _, s := range strings.Fields(scope) {
+		requested[s] = true
+	}
+	if len(requested) == 0 {
+		return auth.ScopeChirpsRead, nil
+	}
+
+	granted := []string{}
+	for _, s := range oauthGrantableScopes {
+		if requested[s] {
+			granted = append(granted, s)
+			delete(requested, s)
+		}
+	}
+	for s := range requested {
+		return "", errors.New("scope " + s + " can't be granted to third-party apps")
+	}
+	return strings.Join(granted, " "), nil
+}
+
+// scopeSubset reports whether every scope in scope is also in of.
+func scopeSubset(scope, of string) bool {
+	have := map[string]bool{}
+	for _, s := range strings.Fields(of) {
+		have[s] = true
+	}
+	for _, s := range strings.Fields(scope) {
+		if !have[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// respondOAuthError writes an error in the RFC 6749 section 5.2 format the
+// token, revocation and introspection endpoints must use.
+func respondOAuthError(w http.ResponseWriter, code int, errCode, description string, err error) {
+	type oauthError struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description,omitempty"`
+	}
+
+	if err != nil {
+		log.Println(err)
+	}
+	if code > 499 {
+		log.Printf("Responding with 5XX error: %s", description)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, code, oauthError{
+		Error:       errCode,
+		Description: description,
+	})
+}
+
+// errInvalidClient is returned by authenticateOAuthClient for unknown
+// clients and bad credentials alike.
+var errInvalidClient = errors.New("invalid client credentials")
+
+// authenticateOAuthClient identifies the client calling a token endpoint,
+// from HTTP Basic credentials or client_id and client_secret form fields
+// (RFC 6749 section 2.3.1). Public clients send only their client_id.
+func (cfg *apiConfig) authenticateOAuthClient(r *http.Request) (database.OauthClient, error) {
+	clientIDString, secret, ok := r.BasicAuth()
+	if ok {
+		// Basic credentials are form-encoded before being combined.
+		clientIDString, _ = url.QueryUnescape(clientIDString)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientIDString = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+
+	clientID, err := uuid.Parse(clientIDString)
+	if err != nil {
+		return database.OauthClient{}, errInvalidClient
+	}
+	client, err := cfg.db.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.OauthClient{}, errInvalidClient
+	}
+	if err != nil {
+		return database.OauthClient{}, err
+	}
+
+	if !client.SecretHash.Valid {
+		if secret != "" {
+			return database.OauthClient{}, errInvalidClient
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OauthClient{}, errInvalidClient
+	}
+	return client, nil
+}
+
+// requireOAuthClient is authenticateOAuthClient for handlers: on failure it
+// responds and returns false.
+func (cfg *apiConfig) requireOAuthClient(w http.ResponseWriter, r *http.Request) (database.OauthClient, bool) {
+	client, err := cfg.authenticateOAuthClient(r)
+	if errors.Is(err, errInvalidClient) {
+		if _, _, ok := r.BasicAuth(); ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		}
+		respondOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed", err)
+		return database.OauthClient{}, false
+	}
+	if err != nil {
+		respondOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't get client", err)
+		return database.OauthClient{}, false
+	}
+	return client, true
+}
+
+// oauthTokens is a token endpoint response, as in RFC 6749 section 5.1.
+type oauthTokens struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// makeOAuthTokens issues an access and refresh token for user to client
+// with the granted scope, saving the refresh token through q. familyID ties
+// the tokens to the grant, which users see and can end like any other
+// session.
+func (cfg *apiConfig) makeOAuthTokens(r *http.Request, q *database.Queries, user database.User, client database.OauthClient, scope string, familyID uuid.UUID) (oauthTokens, error) {
+	accessToken, err := cfg.keyring.MakeAccessToken(auth.AccessToken{
+		UserID:    user.ID,
+		Role:      auth.Role(user.Role),
+		Scopes:    strings.Fields(scope),
+		SessionID: familyID,
+		ClientID:  client.ID.String(),
+		ExpiresIn: accessTokenTTL,
+	})
+	if err != nil {
+		return oauthTokens{}, err
+	}
+
+	refreshToken, err := cfg.createClientRefreshToken(r, q, user.ID, familyID,
+		uuid.NullUUID{UUID: client.ID, Valid: true},
+		sql.NullString{String: scope, Valid: true})
+	if err != nil {
+		return oauthTokens{}, err
+	}
+
+	return oauthTokens{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        scope,
+	}, nil
+}
+
+// respondOAuthTokens writes a successful token endpoint response.
+func respondOAuthTokens(w http.ResponseWriter, tokens oauthTokens) {
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, tokens)
+}
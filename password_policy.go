@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+)
+
+// checkPasswordPolicy validates a new password for the account with email.
+// On failure it responds with 400 and the list of failed rules, and returns
+// false.
+func (cfg *apiConfig) checkPasswordPolicy(w http.ResponseWriter, password, email string) bool {
+	type response struct {
+		Error      string                 `json:"error"`
+		Violations []auth.PolicyViolation `json:"violations"`
+	}
+
+	err := cfg.passwordPolicy.Validate(password, email)
+	if err == nil {
+		return true
+	}
+
+	var policyErr *auth.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		respondWithJSON(w, http.StatusBadRequest, response{
+			Error:      "Password doesn't meet the password policy",
+			Violations: policyErr.Violations,
+		})
+		return false
+	}
+
+	respondWithError(w, http.StatusInternalServerError, "Couldn't check password", err)
+	return false
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/ItSpecOps/go-server/internal/validation"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+)
+
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+// Display names and bios are checked like chirp bodies, with their own
+// length limits. Only bios may span several lines.
+var (
+	displayNamePolicy = validation.TextPolicy{MaxLength: maxDisplayNameLength}
+	bioPolicy         = validation.TextPolicy{MaxLength: maxBioLength, AllowNewlines: true}
+)
+
+// reservedHandles can't be taken because they would read as something
+// other than a user, or collide with routes under /api/users.
+var reservedHandles = map[string]bool{
+	"admin":   true,
+	"api":     true,
+	"chirpy":  true,
+	"me":      true,
+	"restore": true,
+	"root":    true,
+	"support": true,
+	"verify":  true,
+}
+
+// Profile is the public view of a user. It never includes the email
+// address or anything else only the account owner should see.
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// profileFromDB converts a database row into its public representation.
+func profileFromDB(dbUser database.User) Profile {
+	return Profile{
+		ID:          dbUser.ID,
+		Handle:      dbUser.Handle,
+		DisplayName: dbUser.DisplayName,
+		Bio:         dbUser.Bio,
+		AvatarURL:   dbUser.AvatarUrl,
+		CreatedAt:   dbUser.CreatedAt,
+	}
+}
+
+// validateHandle checks a handle without its leading "@". Handles are
+// compared case-insensitively, so reserved names are too.
+func validateHandle(handle string) error {
+	if !handlePattern.MatchString(handle) {
+		return errors.New("handle must be 3-30 letters, digits or underscores")
+	}
+	if reservedHandles[strings.ToLower(handle)] {
+		return errors.New("handle is reserved")
+	}
+	return nil
+}
+
+// validateAvatarURL accepts an empty string, which clears the avatar, or an
+// absolute https URL.
+func validateAvatarURL(avatarURL string) error {
+	if avatarURL == "" {
+		return nil
+	}
+	if len(avatarURL) > maxAvatarURLLength {
+		return errors.New("avatar_url is too long")
+	}
+	u, err := url.Parse(avatarURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("avatar_url must be an https URL")
+	}
+	return nil
+}
+
+// cleanProfileText checks a free-text profile field against policy and
+// returns it normalized. Blank values are allowed and clear the field.
+func cleanProfileText(field, value string, policy validation.TextPolicy) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return "", nil
+	}
+	cleaned, err := policy.Clean(value)
+	if err != nil {
+		return "", errors.New(field + " " + err.Error())
+	}
+	return cleaned, nil
+}
+
+// defaultHandle makes a placeholder handle for a user who didn't choose one,
+// in the same form the profile migration gave existing accounts.
+func defaultHandle() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "user_" + hex.EncodeToString(b), nil
+}
+
+// handleTaken reports whether err is the unique index on handles rejecting
+// a handle another user already has.
+func handleTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "users_handle_lower_idx"
+}
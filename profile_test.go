@@ -0,0 +1,123 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// This test ensures handles are limited to 3-30 word characters and can't
+// take reserved names in any case
+func TestValidateHandle(t *testing.T) {
+	tests := []struct {
+		name    string
+		handle  string
+		wantErr bool
+	}{
+		{name: "Letters and digits", handle: "chirper42"},
+		{name: "Underscores", handle: "_a_b_"},
+		{name: "Shortest", handle: "abc"},
+		{name: "Longest", handle: strings.Repeat("a", 30)},
+		{name: "Reserved name in another case", handle: "iAmNotAdmin"},
+		{name: "Empty", handle: "", wantErr: true},
+		{name: "Too short", handle: "ab", wantErr: true},
+		{name: "Too long", handle: strings.Repeat("a", 31), wantErr: true},
+		{name: "Space", handle: "chirp er", wantErr: true},
+		{name: "Hyphen", handle: "chirp-er", wantErr: true},
+		{name: "Dot", handle: "chirp.er", wantErr: true},
+		{name: "Non-ASCII letter", handle: "chïrper", wantErr: true},
+		{name: "Trailing newline", handle: "chirper\n", wantErr: true},
+		{name: "Reserved", handle: "admin", wantErr: true},
+		{name: "Reserved in upper case", handle: "ADMIN", wantErr: true},
+		{name: "Reserved route", handle: "Me", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateHandle(tt.handle)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateHandle(%q) error = %v, wantErr %v", tt.handle, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// This test ensures avatars are absolute https URLs of bounded length, or
+// empty to clear them
+func TestValidateAvatarURL(t *testing.T) {
+	long := "https://cdn.example.com/" + strings.Repeat("a", maxAvatarURLLength)
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "Empty clears the avatar", url: ""},
+		{name: "https", url: "https://cdn.example.com/avatar.png"},
+		{name: "https with query", url: "https://cdn.example.com/a.png?size=64"},
+		{name: "http", url: "http://cdn.example.com/avatar.png", wantErr: true},
+		{name: "data URL", url: "data:image/png;base64,iVBORw0KGgo=", wantErr: true},
+		{name: "javascript", url: "javascript:alert(1)", wantErr: true},
+		{name: "Protocol-relative", url: "//cdn.example.com/avatar.png", wantErr: true},
+		{name: "Relative", url: "/avatar.png", wantErr: true},
+		{name: "No host", url: "https:///avatar.png", wantErr: true},
+		{name: "Malformed", url: "https://cdn.example.com:port/a.png", wantErr: true},
+		{name: "Control character", url: "https://cdn.example.com/a\x7f.png", wantErr: true},
+		{name: "Too long", url: long, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAvatarURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAvatarURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// This test ensures display names and bios are cleaned by their policies,
+// with errors naming the field
+func TestCleanProfileText(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		value   string
+		bio     bool
+		want    string
+		wantErr string
+	}{
+		{name: "Blank clears the field", field: "display_name", value: "  \n ", want: ""},
+		{name: "Trimmed", field: "display_name", value: "  Ada  ", want: "Ada"},
+		{name: "Longest display name", field: "display_name", value: strings.Repeat("é", maxDisplayNameLength), want: strings.Repeat("é", maxDisplayNameLength)},
+		{name: "Display name too long", field: "display_name", value: strings.Repeat("a", maxDisplayNameLength+1), wantErr: "display_name must be at most"},
+		{name: "Newline in display name", field: "display_name", value: "Ada\nLovelace", wantErr: "display_name "},
+		{name: "Control character in display name", field: "display_name", value: "Ada\x1b[31m", wantErr: "display_name "},
+		{name: "Zero width joiner emoji counts once", field: "display_name", value: strings.Repeat("👩‍💻", maxDisplayNameLength), want: strings.Repeat("👩‍💻", maxDisplayNameLength)},
+		{name: "Newline in bio", field: "bio", bio: true, value: "line one\r\nline two", want: "line one\nline two"},
+		{name: "Null byte in bio", field: "bio", bio: true, value: "hi\x00there", wantErr: "bio "},
+		{name: "Bio too long", field: "bio", bio: true, value: strings.Repeat("a", maxBioLength+1), wantErr: "bio must be at most"},
+		{name: "Invalid UTF-8 in bio", field: "bio", bio: true, value: "caf\xe9", wantErr: "bio "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := displayNamePolicy
+			if tt.bio {
+				policy = bioPolicy
+			}
+			got, err := cleanProfileText(tt.field, tt.value, policy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("cleanProfileText() error = %v, want prefix %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cleanProfileText() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("cleanProfileText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
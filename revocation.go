@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/cache"
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+// revocationCacheTTL bounds how long a revocation made by another server
+// instance can go unnoticed here. Revocations made through this instance
+// apply at once.
+const revocationCacheTTL = 30 * time.Second
+
+// tokenRevocations answers whether an access token has been revoked, in
+// either of two ways: its jti was denylisted or its session was ended.
+// Signing a user out everywhere ends each of their sessions, so it needs no
+// comparison of issue times across clocks. Answers come from
+// Postgres and are cached in memory. A revocation is permanent, so a
+// positive answer is cached until the token would have expired anyway.
+type tokenRevocations struct {
+	db       *database.Queries
+	tokens   *cache.TTL[string, bool]
+	sessions *cache.TTL[uuid.UUID, bool]
+}
+
+func newTokenRevocations(db *database.Queries) *tokenRevocations {
+	return &tokenRevocations{
+		db:       db,
+		tokens:   cache.New[string, bool](),
+		sessions: cache.New[uuid.UUID, bool](),
+	}
+}
+
+// Revoked implements auth.Revocations.
+func (t *tokenRevocations) Revoked(ctx context.Context, claims *auth.Claims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := t.tokenRevoked(ctx, claims.ID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	if claims.SessionID != "" {
+		sessionID, err := uuid.Parse(claims.SessionID)
+		if err != nil {
+			return false, err
+		}
+		return t.sessionEnded(ctx, sessionID)
+	}
+	return false, nil
+}
+
+func (t *tokenRevocations) tokenRevoked(ctx context.Context, jti string) (bool, error) {
+	if revoked, ok := t.tokens.Get(jti); ok {
+		return revoked, nil
+	}
+	revoked, err := t.db.IsAccessTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+	t.tokens.Set(jti, revoked, t.cacheTTL(revoked))
+	return revoked, nil
+}
+
+func (t *tokenRevocations) sessionEnded(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	if ended, ok := t.sessions.Get(sessionID); ok {
+		return ended, nil
+	}
+	ended, err := t.db.IsSessionRevoked(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	t.sessions.Set(sessionID, ended, t.cacheTTL(ended))
+	return ended, nil
+}
+
+func (t *tokenRevocations) cacheTTL(revoked bool) time.Duration {
+	if revoked {
+		return accessTokenTTL + jwtLeeway
+	}
+	return revocationCacheTTL
+}
+
+// revokeToken denylists a single access token until it expires.
+func (t *tokenRevocations) revokeToken(ctx context.Context, claims *auth.Claims) error {
+	err := t.db.RevokeAccessToken(ctx, database.RevokeAccessTokenParams{
+		Jti:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return err
+	}
+	t.tokens.Set(claims.ID, true, t.cacheTTL(true))
+	// Expired entries can't match a token that would validate, so this is a
+	// convenient moment to drop them.
+	return t.db.DeleteExpiredRevokedAccessTokens(ctx)
+}
+
+// endSession records that a session was ended, so this instance rejects its
+// access tokens without waiting for the cache. The caller revokes the
+// session's refresh tokens.
+func (t *tokenRevocations) endSession(sessionID uuid.UUID) {
+	t.sessions.Set(sessionID, true, t.cacheTTL(true))
+}
+
+// endSessions is endSession for each session RevokeUserRefreshTokens
+// ended. Call it once any transaction that ended them has committed.
+func (t *tokenRevocations) endSessions(sessionIDs []uuid.UUID) {
+	for _, sessionID := range sessionIDs {
+		t.endSession(sessionID)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+// Session is a login as shown to the user: one refresh token family and the
+// device that holds it.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session the request was made from.
+	Current bool `json:"current"`
+	// ClientID is set for sessions that are an OAuth client's access to
+	// the account rather than a login.
+	ClientID *uuid.UUID `json:"client_id,omitempty"`
+}
+
+// sessionFromDB converts a database row into its API representation.
+func sessionFromDB(row database.ListUserSessionsRow, currentID uuid.UUID) Session {
+	session := Session{
+		ID:         row.FamilyID,
+		UserAgent:  row.UserAgent,
+		IP:         row.Ip,
+		CreatedAt:  row.StartedAt,
+		LastUsedAt: row.LastUsedAt,
+		ExpiresAt:  row.ExpiresAt,
+		Current:    row.FamilyID == currentID,
+	}
+	if row.ClientID.Valid {
+		session.ClientID = &row.ClientID.UUID
+	}
+	return session
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+)
+
+// loadKeyring builds the JWT keyring from the environment:
+//
+//   - JWT_SIGNING_KEY_FILE: PEM private key (RSA, ECDSA or Ed25519) used to
+//     sign new tokens. When unset, tokens are signed with JWT_SECRET (HS256).
+//   - JWT_PREVIOUS_KEY_FILES: comma-separated PEM private keys that signed
+//     tokens before the last rotation. They are accepted for verification
+//     only, for JWT_ROTATION_WINDOW after startup.
+//   - JWT_SECRET: shared HS256 secret. When an asymmetric signing key is
+//     configured it is kept for verification during the rotation window so
+//     tokens issued before the switch aren't rejected.
+func loadKeyring() (*auth.Keyring, error) {
+	jwtSecret := os.Getenv("JWT_SECRET")
+	signingKeyFile := os.Getenv("JWT_SIGNING_KEY_FILE")
+
+	window := accessTokenTTL
+	if s := os.Getenv("JWT_ROTATION_WINDOW"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_ROTATION_WINDOW: %w", err)
+		}
+		window = d
+	}
+	retireAt := time.Now().Add(window)
+
+	var keyring *auth.Keyring
+	switch {
+	case signingKeyFile != "":
+		key, err := readSigningKey(signingKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keyring = auth.NewKeyring(key)
+		if jwtSecret != "" {
+			keyring.AddVerificationKey(auth.NewHMACKey("", []byte(jwtSecret)), retireAt)
+		}
+	case jwtSecret != "":
+		keyring = auth.NewKeyring(auth.NewHMACKey("", []byte(jwtSecret)))
+	default:
+		return nil, errors.New("JWT_SIGNING_KEY_FILE or JWT_SECRET must be set")
+	}
+
+	for _, path := range strings.Split(os.Getenv("JWT_PREVIOUS_KEY_FILES"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := readSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keyring.AddVerificationKey(key, retireAt)
+	}
+
+	return keyring, nil
+}
+
+func readSigningKey(path string) (*auth.SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := auth.ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
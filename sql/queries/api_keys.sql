@@ -0,0 +1,43 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (id, user_id, key_id, key_hash, name, scopes, created_at, expires_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW(),
+    $6
+)
+RETURNING *;
+
+-- name: GetAPIKeyByKeyID :one
+SELECT * FROM api_keys
+WHERE key_id = $1
+AND revoked_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: ListUserAPIKeys :many
+SELECT * FROM api_keys
+WHERE user_id = $1
+AND revoked_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: CountUserAPIKeys :one
+SELECT COUNT(*) FROM api_keys
+WHERE user_id = $1
+AND revoked_at IS NULL;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys SET revoked_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND revoked_at IS NULL;
+
+-- name: TouchAPIKey :exec
+-- Only written once a minute per key, so busy bots don't turn every request
+-- into a write.
+UPDATE api_keys SET last_used_at = NOW()
+WHERE id = $1
+AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
@@ -1,13 +1,33 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, root_id)
 VALUES (
-   gen_random_uuid (), now (), now (), $1, $2
+   gen_random_uuid (), now (), now (), $1, $2, $3, $4
 )
 RETURNING *;
 
 -- name: GetChirps :many
 SELECT * FROM chirps ORDER BY created_at ASC;
 
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
 -- name: GetChirp :one
 SELECT * FROM chirps WHERE id = $1;
 
@@ -16,4 +36,49 @@ DELETE FROM chirps;
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: ListUserChirps :many
+SELECT * FROM chirps
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC;
+
+-- name: EditChirp :one
+-- The edit window is checked here as well as by the handler, so a request
+-- that started just inside it can't land after it closes.
+UPDATE chirps
+SET body = sqlc.arg('body'), updated_at = NOW(), edited_at = NOW()
+WHERE id = sqlc.arg('id')
+AND user_id = sqlc.arg('user_id')
+AND created_at > sqlc.arg('editable_after')::timestamp
+RETURNING *;
+
+-- name: ListChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY replaced_at ASC, id ASC;
+
+-- name: GetChirpThread :many
+-- Walks the replies under a chirp, up to max_depth levels down. path sorts
+-- the thread depth-first, each chirp before its replies and siblings
+-- oldest first, and is what pages are cut on.
+WITH RECURSIVE thread AS (
+    SELECT
+        chirps.*,
+        0 AS depth,
+        (to_char(chirps.created_at, 'YYYYMMDDHH24MISSUS') || chirps.id::text)::text AS path
+    FROM chirps
+    WHERE chirps.id = sqlc.arg('id')
+    UNION ALL
+    SELECT
+        chirps.*,
+        thread.depth + 1,
+        (thread.path || '/' || to_char(chirps.created_at, 'YYYYMMDDHH24MISSUS') || chirps.id::text)::text
+    FROM chirps
+    JOIN thread ON chirps.in_reply_to_id = thread.id
+    WHERE thread.depth < sqlc.arg('max_depth')::int
+)
+SELECT * FROM thread
+WHERE thread.path > sqlc.arg('after_path')::text
+ORDER BY thread.path
+LIMIT sqlc.arg('page_size');
@@ -0,0 +1,29 @@
+-- name: RecordLoginAttempt :one
+-- Counts an attempt before its credentials are checked. The row stays
+-- locked until the transaction ends, so concurrent attempts are counted one
+-- at a time.
+INSERT INTO login_attempts (key, failed_count, last_failed_at)
+VALUES (sqlc.arg('key'), 1, NOW())
+ON CONFLICT (key) DO UPDATE SET
+    failed_count = CASE
+        WHEN login_attempts.last_failed_at < sqlc.arg('reset_before')::timestamp THEN 1
+        ELSE login_attempts.failed_count + 1
+    END,
+    last_failed_at = NOW()
+RETURNING failed_count, blocked_until;
+
+-- name: BlockLoginAttempts :exec
+UPDATE login_attempts SET blocked_until = $2
+WHERE key = $1;
+
+-- name: ForgiveLoginAttempt :exec
+-- Takes back an attempt whose credentials turned out to be right, along
+-- with the block it set in case they weren't.
+UPDATE login_attempts SET failed_count = failed_count - 1,
+blocked_until = NULL
+WHERE key = $1
+AND failed_count > 0;
+
+-- name: ClearLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE key = $1;
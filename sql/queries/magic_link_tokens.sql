@@ -0,0 +1,25 @@
+-- name: CreateMagicLinkToken :exec
+INSERT INTO magic_link_tokens (token_hash, user_id, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    $3
+);
+
+-- name: CountRecentMagicLinkTokens :one
+SELECT COUNT(*) FROM magic_link_tokens
+WHERE user_id = sqlc.arg('user_id')
+AND created_at > sqlc.arg('since');
+
+-- name: UseMagicLinkToken :one
+UPDATE magic_link_tokens SET used_at = NOW()
+WHERE token_hash = $1
+AND used_at IS NULL
+AND expires_at > NOW()
+RETURNING *;
+
+-- name: InvalidateMagicLinkTokens :exec
+UPDATE magic_link_tokens SET used_at = NOW()
+WHERE user_id = $1
+AND used_at IS NULL;
@@ -0,0 +1,29 @@
+-- name: CreateModerationFlag :exec
+INSERT INTO moderation_flags (id, chirp_id, rule, excerpt, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    NOW()
+);
+
+-- name: ListOpenModerationFlags :many
+SELECT
+    moderation_flags.id,
+    moderation_flags.rule,
+    moderation_flags.excerpt,
+    moderation_flags.created_at,
+    chirps.id AS chirp_id,
+    chirps.body AS chirp_body,
+    chirps.user_id AS chirp_user_id
+FROM moderation_flags
+JOIN chirps ON chirps.id = moderation_flags.chirp_id
+WHERE moderation_flags.resolved_at IS NULL
+ORDER BY moderation_flags.created_at ASC
+LIMIT $1;
+
+-- name: ResolveModerationFlag :execrows
+UPDATE moderation_flags SET resolved_at = NOW(), resolved_by = $2
+WHERE id = $1
+AND resolved_at IS NULL;
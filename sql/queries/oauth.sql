@@ -0,0 +1,67 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, created_at, updated_at, owner_id, name, secret_hash, redirect_uris)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1;
+
+-- name: ListUserOAuthClients :many
+SELECT * FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY created_at ASC;
+
+-- name: LockOAuthClient :one
+-- Taken before revoking a client's tokens, so it can't be issued new ones
+-- until it is deleted.
+SELECT * FROM oauth_clients
+WHERE id = $1
+AND owner_id = $2
+FOR UPDATE;
+
+-- name: RevokeOAuthClientRefreshTokens :many
+UPDATE refresh_tokens SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE client_id = $1
+AND revoked_at IS NULL
+RETURNING family_id;
+
+-- name: DeleteOAuthClient :exec
+-- Codes issued to the client cascade; its refresh tokens are kept, detached,
+-- so their sessions still read as ended.
+DELETE FROM oauth_clients
+WHERE id = $1;
+
+-- name: CreateOAuthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scope, code_challenge, family_id, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    NOW(),
+    $8
+);
+
+-- name: UseOAuthAuthorizationCode :execrows
+UPDATE oauth_authorization_codes SET used_at = NOW()
+WHERE code_hash = $1
+AND client_id = $2
+AND used_at IS NULL
+AND expires_at > NOW();
+
+-- name: GetOAuthAuthorizationCode :one
+SELECT * FROM oauth_authorization_codes
+WHERE code_hash = $1;
@@ -1,23 +1,105 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, family_id, user_agent, ip, last_used_at, client_id, scope)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
-    $3
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    $7,
+    $8
 )
 RETURNING *;
 
+-- name: GetRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1;
+
 -- name: RevokeRefreshToken :one
 UPDATE refresh_tokens SET revoked_at = NOW(),
 updated_at = NOW()
-WHERE token = $1
+WHERE token_hash = $1
+RETURNING *;
+
+-- name: RotateRefreshToken :one
+-- Tokens issued to OAuth clients can only be refreshed by the client.
+UPDATE refresh_tokens SET revoked_at = NOW(),
+rotated_at = NOW(),
+updated_at = NOW()
+WHERE token_hash = $1
+AND client_id IS NULL
+AND revoked_at IS NULL
+AND expires_at > NOW()
+RETURNING *;
+
+-- name: RotateOAuthRefreshToken :one
+UPDATE refresh_tokens SET revoked_at = NOW(),
+rotated_at = NOW(),
+updated_at = NOW()
+WHERE token_hash = $1
+AND client_id = $2
+AND revoked_at IS NULL
+AND expires_at > NOW()
 RETURNING *;
 
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE family_id = $1
+AND revoked_at IS NULL;
+
 -- name: GetUserFromRefreshToken :one
 SELECT users.* FROM users
 JOIN refresh_tokens ON users.id = refresh_tokens.user_id
-WHERE refresh_tokens.token = $1
+WHERE refresh_tokens.token_hash = $1
+AND revoked_at IS NULL
+AND expires_at > NOW();
+
+-- name: RevokeUserRefreshTokens :many
+-- Ends every session the user has, returning them so their access tokens
+-- can be rejected at once.
+UPDATE refresh_tokens SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE user_id = $1
 AND revoked_at IS NULL
-AND expires_at > NOW();
\ No newline at end of file
+RETURNING family_id;
+
+-- name: ListUserSessions :many
+-- One row per session: the live token of each of the user's families.
+SELECT
+    refresh_tokens.family_id,
+    refresh_tokens.user_agent,
+    refresh_tokens.ip,
+    refresh_tokens.last_used_at,
+    refresh_tokens.expires_at,
+    refresh_tokens.client_id,
+    (
+        SELECT MIN(first.created_at) FROM refresh_tokens AS first
+        WHERE first.family_id = refresh_tokens.family_id
+    )::timestamp AS started_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+AND refresh_tokens.revoked_at IS NULL
+AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC;
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE family_id = $1
+AND user_id = $2
+AND revoked_at IS NULL;
+
+-- name: IsSessionRevoked :one
+-- Rotation revokes tokens too, but sets rotated_at; a token revoked without
+-- being rotated means the session was ended.
+SELECT EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE family_id = $1
+    AND revoked_at IS NOT NULL
+    AND rotated_at IS NULL
+);
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
    gen_random_uuid (),
    now (),
    now (),
    $1,
-   $2 
+   $2,
+   $3
 )
 RETURNING *;
 
@@ -13,10 +14,85 @@ RETURNING *;
 SELECT * FROM users
 WHERE email = $1;
 
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: GetUserByHandle :one
+-- Accounts pending deletion no longer have a public profile.
+SELECT * FROM users
+WHERE lower(handle) = lower($1)
+AND delete_after IS NULL;
+
+-- name: GetUsersByIDs :many
+-- Like GetUserByHandle, leaves out accounts pending deletion.
+SELECT * FROM users
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+AND delete_after IS NULL;
+
+-- name: UpdateUserProfile :one
+UPDATE users SET
+    handle = COALESCE(sqlc.narg('handle'), handle),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    avatar_url = COALESCE(sqlc.narg('avatar_url'), avatar_url),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: UpdateUserRole :one
+UPDATE users SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
 -- name: Reset :exec
 DELETE FROM users;
 
--- name: UpdateUser :one
-UPDATE users SET email = $2, hashed_password = $3, updated_at = NOW()
+-- name: UpdateUserEmail :one
+-- A new email address has to be verified again.
+UPDATE users SET email = $2, updated_at = NOW(),
+email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUserPasswordHash :exec
+UPDATE users SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserTOTPSecret :exec
+UPDATE users SET totp_secret = $2, totp_enabled_at = NULL, totp_last_used_step = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableUserTOTP :exec
+UPDATE users SET totp_enabled_at = NOW(), totp_last_used_step = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE users SET totp_last_used_step = $2
+WHERE id = $1 AND (totp_last_used_step IS NULL OR totp_last_used_step < $2);
+
+-- name: DisableUserTOTP :exec
+UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_used_step = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: VerifyUserEmail :one
+UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email = $2 AND email_verified_at IS NULL
+RETURNING *;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users SET deletion_requested_at = NOW(), delete_after = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserDeletion :one
+UPDATE users SET deletion_requested_at = NULL, delete_after = NULL, updated_at = NOW()
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: PurgeDeletedUsers :many
+-- Chirps, tokens and everything else owned by the user cascade.
+DELETE FROM users
+WHERE delete_after IS NOT NULL
+AND delete_after <= NOW()
+RETURNING id, email;
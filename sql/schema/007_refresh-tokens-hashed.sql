@@ -0,0 +1,16 @@
+-- +goose Up
+-- Refresh tokens are 256-bit random values, so an unsalted SHA-256 is enough
+-- to make a database dump useless for impersonation. Existing rows are
+-- rehashed in place so current sessions survive the migration.
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+
+-- +goose Down
+-- Hashes can't be reversed, so every stored session is dropped.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
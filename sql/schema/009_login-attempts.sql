@@ -0,0 +1,11 @@
+-- +goose Up
+-- Failed logins are counted per key: "email:<address>" and "ip:<address>".
+CREATE TABLE login_attempts(
+    key TEXT PRIMARY KEY,
+    failed_count INTEGER NOT NULL,
+    last_failed_at TIMESTAMP NOT NULL,
+    blocked_until TIMESTAMP NULL
+);
+
+-- +goose Down
+DROP TABLE login_attempts;
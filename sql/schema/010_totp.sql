@@ -0,0 +1,26 @@
+-- +goose Up
+-- totp_secret is set on enrollment; two-factor is only on once
+-- totp_enabled_at is set by confirming a code. totp_last_used_step stops a
+-- code from being replayed within its window.
+ALTER TABLE users
+ADD COLUMN totp_secret TEXT NULL,
+ADD COLUMN totp_enabled_at TIMESTAMP NULL,
+ADD COLUMN totp_last_used_step BIGINT NULL;
+
+CREATE TABLE recovery_codes(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL
+);
+
+CREATE INDEX recovery_codes_user_id_idx ON recovery_codes(user_id);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+
+ALTER TABLE users
+DROP COLUMN totp_secret,
+DROP COLUMN totp_enabled_at,
+DROP COLUMN totp_last_used_step;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- A session is a refresh token family. Each token records the device that
+-- obtained it; the live token of a family describes the session.
+ALTER TABLE refresh_tokens
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+ADD COLUMN ip TEXT NOT NULL DEFAULT '',
+ADD COLUMN last_used_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+UPDATE refresh_tokens SET last_used_at = updated_at;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN user_agent DROP DEFAULT,
+ALTER COLUMN ip DROP DEFAULT,
+ALTER COLUMN last_used_at DROP DEFAULT;
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_user_id_idx;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN last_used_at,
+DROP COLUMN ip,
+DROP COLUMN user_agent;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Denylisted access tokens, kept until they would have expired anyway.
+CREATE TABLE revoked_access_tokens(
+    jti TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    revoked_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX revoked_access_tokens_expires_at_idx ON revoked_access_tokens(expires_at);
+
+-- +goose Down
+DROP TABLE revoked_access_tokens;
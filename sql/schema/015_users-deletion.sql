@@ -0,0 +1,16 @@
+-- +goose Up
+-- Accounts the user asked to delete are kept until delete_after, so the
+-- deletion can be undone, then removed by the purge job.
+ALTER TABLE users
+ADD COLUMN deletion_requested_at TIMESTAMP NULL,
+ADD COLUMN delete_after TIMESTAMP NULL;
+
+CREATE INDEX users_delete_after_idx ON users(delete_after)
+WHERE delete_after IS NOT NULL;
+
+-- +goose Down
+DROP INDEX users_delete_after_idx;
+
+ALTER TABLE users
+DROP COLUMN delete_after,
+DROP COLUMN deletion_requested_at;
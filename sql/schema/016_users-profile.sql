@@ -0,0 +1,24 @@
+-- +goose Up
+-- Handles are stored as the user typed them but unique regardless of case.
+ALTER TABLE users
+ADD COLUMN handle TEXT NULL,
+ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '';
+
+-- Existing accounts get a placeholder handle they can change later.
+UPDATE users SET handle = 'user_' || left(replace(id::text, '-', ''), 12);
+
+ALTER TABLE users
+ALTER COLUMN handle SET NOT NULL;
+
+CREATE UNIQUE INDEX users_handle_lower_idx ON users(lower(handle));
+
+-- +goose Down
+DROP INDEX users_handle_lower_idx;
+
+ALTER TABLE users
+DROP COLUMN avatar_url,
+DROP COLUMN bio,
+DROP COLUMN display_name,
+DROP COLUMN handle;
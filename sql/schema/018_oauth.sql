@@ -0,0 +1,45 @@
+-- +goose Up
+-- Third-party apps registered to act on users' behalf. Public clients (such
+-- as mobile apps) have no secret and rely on PKCE alone.
+CREATE TABLE oauth_clients(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    secret_hash TEXT NULL,
+    redirect_uris TEXT[] NOT NULL
+);
+
+CREATE INDEX oauth_clients_owner_id_idx ON oauth_clients(owner_id);
+
+-- Only the SHA-256 of each authorization code is stored. family_id is
+-- chosen up front so a replayed code can revoke the tokens it was
+-- exchanged for.
+CREATE TABLE oauth_authorization_codes(
+    code_hash TEXT PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    code_challenge TEXT NOT NULL,
+    family_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL
+);
+
+-- Refresh tokens issued to a client carry its ID and the scope the user
+-- granted; first-party tokens have neither. Deleting a client revokes its
+-- tokens and keeps them, detached, so their sessions still read as ended.
+ALTER TABLE refresh_tokens
+ADD COLUMN client_id UUID NULL REFERENCES oauth_clients(id) ON DELETE SET NULL,
+ADD COLUMN scope TEXT NULL;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP COLUMN scope,
+DROP COLUMN client_id;
+
+DROP TABLE oauth_authorization_codes;
+DROP TABLE oauth_clients;
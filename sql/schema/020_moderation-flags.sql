@@ -0,0 +1,17 @@
+-- +goose Up
+-- Chirps that matched a rule with the flag action, waiting for a moderator.
+CREATE TABLE moderation_flags(
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    rule TEXT NOT NULL,
+    excerpt TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP NULL,
+    resolved_by UUID NULL REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX moderation_flags_open_idx ON moderation_flags(created_at)
+WHERE resolved_at IS NULL;
+
+-- +goose Down
+DROP TABLE moderation_flags;
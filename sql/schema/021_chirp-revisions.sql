@@ -0,0 +1,41 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN edited_at TIMESTAMP NULL;
+
+-- Every body a chirp had before an edit. The trigger below fills it in, so
+-- no edit can skip its revision, and concurrent edits each record the body
+-- they replaced.
+CREATE TABLE chirp_revisions(
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    -- written_at is when this body was posted or last edited in.
+    written_at TIMESTAMP NOT NULL,
+    replaced_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX chirp_revisions_chirp_id_idx ON chirp_revisions(chirp_id, replaced_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION chirps_save_revision() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO chirp_revisions (id, chirp_id, body, written_at, replaced_at)
+    VALUES (gen_random_uuid(), OLD.id, OLD.body, OLD.updated_at, NOW());
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_save_revision
+BEFORE UPDATE OF body ON chirps
+FOR EACH ROW
+WHEN (OLD.body IS DISTINCT FROM NEW.body)
+EXECUTE FUNCTION chirps_save_revision();
+
+-- +goose Down
+DROP TRIGGER chirps_save_revision ON chirps;
+DROP FUNCTION chirps_save_revision();
+DROP TABLE chirp_revisions;
+
+ALTER TABLE chirps
+DROP COLUMN edited_at;
@@ -0,0 +1,47 @@
+-- +goose Up
+-- Replies point at the chirp they answer and at the first chirp of the
+-- conversation. A deleted chirp leaves its replies in place, detached.
+ALTER TABLE chirps
+ADD COLUMN in_reply_to_id UUID NULL REFERENCES chirps(id) ON DELETE SET NULL,
+ADD COLUMN root_id UUID NULL REFERENCES chirps(id) ON DELETE SET NULL,
+ADD COLUMN reply_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX chirps_in_reply_to_id_idx ON chirps(in_reply_to_id)
+WHERE in_reply_to_id IS NOT NULL;
+CREATE INDEX chirps_root_id_idx ON chirps(root_id)
+WHERE root_id IS NOT NULL;
+
+-- reply_count counts direct replies. Keeping it in a trigger means every
+-- way a chirp is created or deleted, including cascades from deleted
+-- users, keeps it right.
+-- +goose StatementBegin
+CREATE FUNCTION chirps_count_replies() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.in_reply_to_id IS NOT NULL THEN
+        UPDATE chirps SET reply_count = reply_count + 1
+        WHERE id = NEW.in_reply_to_id;
+    ELSIF TG_OP = 'DELETE' AND OLD.in_reply_to_id IS NOT NULL THEN
+        UPDATE chirps SET reply_count = reply_count - 1
+        WHERE id = OLD.in_reply_to_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_count_replies
+AFTER INSERT OR DELETE ON chirps
+FOR EACH ROW
+EXECUTE FUNCTION chirps_count_replies();
+
+-- +goose Down
+DROP TRIGGER chirps_count_replies ON chirps;
+DROP FUNCTION chirps_count_replies();
+
+DROP INDEX chirps_root_id_idx;
+DROP INDEX chirps_in_reply_to_id_idx;
+
+ALTER TABLE chirps
+DROP COLUMN reply_count,
+DROP COLUMN root_id,
+DROP COLUMN in_reply_to_id;
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	accessTokenTTL  = time.Hour
+	refreshTokenTTL = 60 * 24 * time.Hour
+
+	// jwtLeeway is the clock skew tolerated between us and whoever minted
+	// or is checking a token.
+	jwtLeeway = 30 * time.Second
+
+	// maxUserAgentLength caps the User-Agent stored for a session.
+	maxUserAgentLength = 512
+)
+
+// accessTokenOptions are the checks every access token must pass: signed
+// with one of our keys' algorithms, issued by us for our API, carrying a
+// subject, expiry, issue time and session, and not revoked. Requiring the
+// session means ending it always reaches the token.
+func accessTokenOptions(keyring *auth.Keyring, revocations auth.Revocations) auth.ValidatorOptions {
+	return auth.ValidatorOptions{
+		Algorithms:     keyring.Algorithms(),
+		Issuer:         auth.Issuer,
+		Audiences:      []string{auth.AccessTokenAudience},
+		Leeway:         jwtLeeway,
+		RequiredClaims: []string{"sub", "exp", "iat", "iss", "aud", "sid"},
+		Revocations:    revocations,
+	}
+}
+
+// makeAccessToken issues an access token for user in the given session,
+// carrying their role and every scope that role allows.
+func (cfg *apiConfig) makeAccessToken(user database.User, sessionID uuid.UUID) (string, error) {
+	role := auth.Role(user.Role)
+	return cfg.keyring.MakeAccessToken(auth.AccessToken{
+		UserID:    user.ID,
+		Role:      role,
+		Scopes:    role.Scopes(),
+		SessionID: sessionID,
+		ExpiresIn: accessTokenTTL,
+	})
+}
+
+// createRefreshToken issues a new refresh token for userID as part of the
+// given token family. Every token handed out by a single login shares a
+// family, so the whole chain can be revoked at once if a rotated token is
+// ever presented again. The family is also the session users see, and r
+// supplies the device details shown for it. The token is saved through q,
+// so it can be part of a transaction.
+func (cfg *apiConfig) createRefreshToken(r *http.Request, q *database.Queries, userID, familyID uuid.UUID) (string, error) {
+	return cfg.createClientRefreshToken(r, q, userID, familyID, uuid.NullUUID{}, sql.NullString{})
+}
+
+// createClientRefreshToken is createRefreshToken for a token issued to an
+// OAuth client, which records the client and the scope the user granted it.
+// First-party tokens pass neither.
+func (cfg *apiConfig) createClientRefreshToken(r *http.Request, q *database.Queries, userID, familyID uuid.UUID, clientID uuid.NullUUID, scope sql.NullString) (string, error) {
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	_, err = q.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		TokenHash: auth.HashToken(refreshToken),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+		FamilyID:  familyID,
+		UserAgent: userAgent,
+		Ip:        cfg.clientIP(r),
+		ClientID:  clientID,
+		Scope:     scope,
+	})
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ItSpecOps/go-server/internal/auth"
+	"github.com/ItSpecOps/go-server/internal/database"
+)
+
+const (
+	// totpIssuer names us in the user's authenticator app.
+	totpIssuer = "Chirpy"
+	// twoFactorChallengeTTL is how long a client has to enter a code after
+	// getting the password right.
+	twoFactorChallengeTTL = 5 * time.Minute
+	recoveryCodeCount     = 10
+)
+
+var errSecondFactorRejected = errors.New("second factor rejected")
+
+// challengeTokenOptions are the checks a login challenge token must pass.
+// Only the audience differs from access tokens, which is what keeps the
+// two from being swapped.
+func challengeTokenOptions(keyring *auth.Keyring) auth.ValidatorOptions {
+	return auth.ValidatorOptions{
+		Algorithms:     keyring.Algorithms(),
+		Issuer:         auth.Issuer,
+		Audiences:      []string{auth.TwoFactorAudience},
+		Leeway:         jwtLeeway,
+		RequiredClaims: []string{"sub", "exp", "iat", "iss", "aud"},
+	}
+}
+
+// checkSecondFactor accepts either a TOTP code or a recovery code for user.
+// A TOTP code is burned by recording its time step and a recovery code by
+// marking it used, so neither works twice.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, user database.User, code, recoveryCode string) error {
+	if !user.TotpSecret.Valid {
+		return errSecondFactorRejected
+	}
+
+	if recoveryCode != "" {
+		used, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashRecoveryCode(recoveryCode),
+		})
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return errSecondFactorRejected
+		}
+		return nil
+	}
+
+	step, err := auth.ValidateTOTP(user.TotpSecret.String, code, time.Now())
+	if errors.Is(err, auth.ErrInvalidTOTPCode) {
+		return errSecondFactorRejected
+	}
+	if err != nil {
+		return err
+	}
+	used, err := cfg.db.UseUserTOTPStep(ctx, database.UseUserTOTPStepParams{
+		ID:               user.ID,
+		TotpLastUsedStep: sql.NullInt64{Int64: step, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return errSecondFactorRejected
+	}
+	return nil
+}
+
+// verifySecondFactor runs checkSecondFactor behind the same throttle as
+// passwords, since six digits fall quickly to guessing. On failure it
+// responds and returns false.
+func (cfg *apiConfig) verifySecondFactor(w http.ResponseWriter, r *http.Request, user database.User, code, recoveryCode string) bool {
+	key := throttledKey{key: emailThrottleKey(user.Email), throttle: cfg.loginThrottles.email}
+	wait, err := cfg.beginLoginAttempt(r.Context(), key)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check login attempts", err)
+		return false
+	}
+	if wait > 0 {
+		respondLoginBlocked(w, wait)
+		return false
+	}
+
+	err = cfg.checkSecondFactor(r.Context(), user, code, recoveryCode)
+	if errors.Is(err, errSecondFactorRejected) {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect two-factor code", err)
+		return false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check two-factor code", err)
+		return false
+	}
+	cfg.forgiveLoginAttempt(r.Context(), key)
+	return true
+}
+
+// middlewareRequireTwoFactor only lets through users who have confirmed
+// TOTP enrollment. It must be wrapped by middlewareRequireAuth.
+func (cfg *apiConfig) middlewareRequireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := requestPrincipal(r)
+		user, err := cfg.db.GetUserByID(r.Context(), principal.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+			return
+		}
+		if !user.TotpEnabledAt.Valid {
+			respondWithError(w, http.StatusForbidden, "Enable two-factor authentication to do that", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
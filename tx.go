@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ItSpecOps/go-server/internal/database"
+)
+
+// withTx runs fn in a database transaction, committing if it returns nil
+// and rolling back otherwise. fn must make its queries through q.
+func (cfg *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(cfg.db.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
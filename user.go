@@ -2,18 +2,53 @@ package main
 
 import (
 	"time"
+
+	"github.com/ItSpecOps/go-server/internal/database"
 	"github.com/google/uuid"
 )
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	Handle        string    `json:"handle"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
+	AvatarURL     string    `json:"avatar_url"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	// TwoFactorEnabled is true once TOTP enrollment has been confirmed.
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+	// DeleteAfter is when a pending account deletion becomes final.
+	DeleteAfter *time.Time `json:"delete_after,omitempty"`
+	Password    string     `json:"-"`
+}
+
+// userFromDB converts a database row into its API representation.
+func userFromDB(dbUser database.User) User {
+	user := User{
+		ID:               dbUser.ID,
+		CreatedAt:        dbUser.CreatedAt,
+		UpdatedAt:        dbUser.UpdatedAt,
+		Email:            dbUser.Email,
+		Handle:           dbUser.Handle,
+		DisplayName:      dbUser.DisplayName,
+		Bio:              dbUser.Bio,
+		AvatarURL:        dbUser.AvatarUrl,
+		Role:             dbUser.Role,
+		EmailVerified:    dbUser.EmailVerifiedAt.Valid,
+		TwoFactorEnabled: dbUser.TotpEnabledAt.Valid,
+	}
+	if dbUser.DeleteAfter.Valid {
+		user.DeleteAfter = &dbUser.DeleteAfter.Time
+	}
+	return user
 }
 
 type createUserParams struct {
-	Email string `json:"email"`
+	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Handle is optional; users without one get a placeholder.
+	Handle string `json:"handle"`
 }